@@ -0,0 +1,84 @@
+package primes
+
+// Thresholds are where an Auto Primer switches from one algorithm to another.
+type Thresholds struct {
+	// TableMax is how far Auto's table of primes goes. Queries up to it are answered from the
+	// table; IsPrime beyond it uses Miller-Rabin.
+	// It isn't calibrated from benchmarks, as SegmentMin is: a lookup beats Miller-Rabin at any
+	// size, so what TableMax trades is the time and memory to build the table (about 26KiB, at
+	// 1<<16) against how many queries it covers, which depends on the queries, not on the machine.
+	TableMax int
+	// SegmentMin is where PrimesUpTo switches from a one-shot sieve, which is quicker for small n,
+	// to a segmented one, which is quicker (and smaller) once the sieve outgrows the cache.
+	SegmentMin int
+}
+
+// DefaultThresholds are the Thresholds that Auto uses unless told otherwise.
+var DefaultThresholds = Thresholds{
+	TableMax:   1 << 16,
+	SegmentMin: 1 << 20,
+}
+
+// WithThresholds sets the Thresholds for an Auto Primer, e.g. from results.CalibrateThresholds.
+func WithThresholds(t Thresholds) Option {
+	return func(o *options) {
+		o.thresholds = t
+	}
+}
+
+var (
+	_ Primer = Auto()
+)
+
+// auto routes each call to whichever algorithm suits it best.
+type auto struct {
+	opts      options
+	table     primeList
+	segmented *segErat
+}
+
+// Auto returns a Primer that picks an algorithm for each call, by its size: a table of primes
+// for small n, Miller-Rabin for IsPrime on larger n, and a one-shot or segmented sieve for
+// PrimesUpTo on larger n. WithThresholds sets where it switches; WithMaxMemory bounds its sieves.
+func Auto(opts ...Option) Primer {
+	o := newOptions(opts)
+	return &auto{
+		opts:      o,
+		table:     basePrimes(o.thresholds.TableMax),
+		segmented: &segErat{opts: o},
+	}
+}
+
+// oneShot returns whether PrimesUpTo(n) should use the one-shot sieve, rather than the
+// segmented one.
+func (p *auto) oneShot(n int) bool {
+	return n < p.opts.thresholds.SegmentMin && checkOdds(n, p.opts.maxMemory) == nil
+}
+
+func (p *auto) check(n int) error {
+	if n <= p.opts.thresholds.TableMax || p.oneShot(n) {
+		return nil
+	}
+	return p.segmented.check(n)
+}
+
+func (p *auto) PrimesUpTo(n int, out chan<- int) {
+	switch {
+	case n <= p.opts.thresholds.TableMax:
+		for k := 0; k < p.table.len() && p.table.at(k) <= n; k++ {
+			out <- p.table.at(k)
+		}
+		close(out)
+	case p.oneShot(n):
+		(&erat5{presieved: true}).PrimesUpTo(n, out)
+	default:
+		p.segmented.PrimesUpTo(n, out)
+	}
+}
+
+func (p *auto) IsPrime(n int) bool {
+	if n <= p.opts.thresholds.TableMax {
+		return n >= 2 && p.table.contains(n)
+	}
+	return millerRabin(n)
+}
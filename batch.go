@@ -0,0 +1,69 @@
+package primes
+
+import (
+	"sort"
+)
+
+const (
+	// batchWindow is the span of numbers that IsPrimeBatch sieves at a time; the odd ones
+	// among them make a segment of defaultSegmentSize.
+	batchWindow = 2 * defaultSegmentSize
+	// batchDense is how many queries a window needs before IsPrimeBatch sieves it, rather than
+	// testing each query with Miller-Rabin. Sieving a window costs about as much as this many tests.
+	batchDense = 2048
+	// batchSieveMax is as far as IsPrimeBatch sieves. Beyond it, finding the primes up to the
+	// square root to sieve with costs more than sieving saves.
+	batchSieveMax = 1 << 40
+)
+
+// IsPrimeBatch reports whether each of ns is prime. It's much cheaper than calling IsPrime for
+// each: it sieves just the windows of numbers that hold many of the queries, and tests the rest
+// with Miller-Rabin. Its memory use is bounded, however large or many ns are.
+func IsPrimeBatch(ns []int) []bool {
+	result := make([]bool, len(ns))
+
+	// Count the odd queries in each window; only odd numbers need any work.
+	counts := make(map[int]int)
+	for i, n := range ns {
+		switch {
+		case n == 2:
+			result[i] = true
+		case n > 2 && n%2 == 1 && n < batchSieveMax:
+			counts[n/batchWindow]++
+		}
+	}
+
+	// Collect the queries in windows that are worth sieving; answer the rest now.
+	dense := make(map[int][]int) // indices into ns, by window
+	for i, n := range ns {
+		if n <= 2 || n%2 == 0 {
+			continue
+		}
+		if k := n / batchWindow; n < batchSieveMax && counts[k] >= batchDense {
+			dense[k] = append(dense[k], i)
+		} else {
+			result[i] = millerRabin(n)
+		}
+	}
+	if len(dense) == 0 {
+		return result
+	}
+	windows := make([]int, 0, len(dense))
+	for k := range dense {
+		windows = append(windows, k)
+	}
+	sort.Ints(windows)
+
+	// The primes to sieve with are those up to the square root of the last window.
+	base := basePrimes(isqrt((windows[len(windows)-1] + 1) * batchWindow))
+
+	composite := make([]bool, defaultSegmentSize)
+	for _, k := range windows {
+		lo := k*batchWindow + 1
+		sieveWindow(composite, lo, lo+batchWindow-2, &base, func(int) {})
+		for _, i := range dense[k] {
+			result[i] = !composite[(ns[i]-lo)/2]
+		}
+	}
+	return result
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cceckman/primes"
+)
+
+// allOps are the operations we know how to benchmark.
+var allOps = []string{"PrimesUpTo", "IsPrime"}
+
+// caseFilter selects which cases to benchmark.
+type caseFilter struct {
+	// impl matches the implementations to benchmark.
+	impl *regexp.Regexp
+	// ops holds the operations to benchmark.
+	ops map[string]bool
+}
+
+// newCaseFilter returns a filter for implementations matching implRE and the comma-separated
+// operations in opList.
+func newCaseFilter(implRE, opList string) (caseFilter, error) {
+	re, err := regexp.Compile(implRE)
+	if err != nil {
+		return caseFilter{}, fmt.Errorf("bad --impl: %v", err)
+	}
+	f := caseFilter{impl: re, ops: make(map[string]bool)}
+	for _, op := range strings.Split(opList, ",") {
+		op = strings.TrimSpace(op)
+		known := false
+		for _, o := range allOps {
+			known = known || o == op
+		}
+		if !known {
+			return caseFilter{}, fmt.Errorf("bad --op: unknown operation %q; want one of %s", op, strings.Join(allOps, ","))
+		}
+		f.ops[op] = true
+	}
+	return f, nil
+}
+
+// names returns the names of the registered implementations that f matches, in order.
+func (f caseFilter) names() []string {
+	var names []string
+	for _, name := range primes.Names() {
+		if f.impl.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// parseInts parses a comma-separated list of integers. An empty string is an empty list.
+func parseInts(s string) ([]int, error) {
+	var r []int
+	if s == "" {
+		return r, nil
+	}
+	for _, v := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("bad list of integers %q: %v", s, err)
+		}
+		r = append(r, n)
+	}
+	return r, nil
+}
+
+// parseLevels returns the arguments for the comma-separated levels in s: level L is 1..1 with
+// L zeros in the middle, as in levelGen. If s is empty, it returns all the levels up to upTo.
+func parseLevels(s string, upTo int) ([]int, error) {
+	if s == "" {
+		return levelGen(upTo), nil
+	}
+	ls, err := parseInts(s)
+	if err != nil {
+		return nil, err
+	}
+	top := 0
+	for _, l := range ls {
+		if l < 1 || l > 17 {
+			// Past 17 zeros, we're out of int64 (and, long before that, patience).
+			return nil, fmt.Errorf("bad level %d: want 1 through 17", l)
+		}
+		top = max(top, l)
+	}
+	all := levelGen(top)
+	r := make([]int, len(ls))
+	for i, l := range ls {
+		r[i] = all[l-1]
+	}
+	return r, nil
+}
+
+// opCase returns a case that calls op with arg on whichever Primer *p is when it runs.
+func opCase(op, impl string, arg int, p *primes.Primer) benchCase {
+	c := benchCase{op: op, impl: impl, arg: arg}
+	switch op {
+	case "PrimesUpTo":
+		c.run = func() fmt.Stringer {
+			ch := make(chan int)
+			go (*p).PrimesUpTo(arg, ch)
+			x := 0
+			for x = range ch {
+			}
+			return printInt(x)
+		}
+	case "IsPrime":
+		c.run = func() fmt.Stringer {
+			return printBool((*p).IsPrime(arg))
+		}
+	}
+	return c
+}
+
+// variants returns the cases for op with arg on the named implementation: just one, unless
+// the implementation is stateful. An instance of one of those would answer every case after
+// the first from its cache, so it's benchmarked twice: "cold", with a fresh instance for every
+// call, and "warm", with an instance that has already answered the same call.
+// The warm case keeps the implementation's plain name, as the results from before the split
+// (e.g. Memo's, in results.tsv) came from a shared instance, and compare with it.
+// Instances are only made, and warmed up, once the case is about to run.
+func variants(op, name string, arg int) []benchCase {
+	factory, info, _ := primes.Lookup(name)
+	var p primes.Primer
+	c := opCase(op, name, arg, &p)
+	c.prepare = func() func() {
+		p = factory()
+		if info.Stateful {
+			c.run()
+		}
+		return func() { p = nil }
+	}
+	if !info.Stateful {
+		return []benchCase{c}
+	}
+
+	var cold primes.Primer
+	coldCase := opCase(op, name+"/cold", arg, &cold)
+	coldCase.setup = func() { cold = factory() }
+	coldCase.prepare = func() func() {
+		return func() { cold = nil }
+	}
+	return []benchCase{coldCase, c}
+}
+
+// makeCases returns the cases to benchmark that match f, in a stable order: by implementation,
+// then by argument. Each level is run with PrimesUpTo(level) and IsPrime(largest prime up to level);
+// each of args is run with PrimesUpTo(arg) and IsPrime(arg).
+func makeCases(levels, args []int, f caseFilter) []benchCase {
+	var cases []benchCase
+
+	for _, name := range f.names() {
+		addCases := func(upTo, isPrime int) {
+			if f.ops["PrimesUpTo"] {
+				cases = append(cases, variants("PrimesUpTo", name, upTo)...)
+			}
+			if f.ops["IsPrime"] {
+				cases = append(cases, variants("IsPrime", name, isPrime)...)
+			}
+		}
+
+		for _, level := range levels {
+			// Test IsPrime with the largest prime up to the level: the worst case for a sieve.
+			// Find it with Miller-Rabin, so listing the cases doesn't sieve.
+			largest, _ := primes.PrevPrime(level + 1)
+			addCases(level, largest)
+		}
+		for _, arg := range args {
+			addCases(arg, arg)
+		}
+	}
+	return cases
+}
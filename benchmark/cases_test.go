@@ -0,0 +1,87 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLevels(t *testing.T) {
+	got, err := parseLevels("3,1,5", 2)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if want := []int{10001, 101, 1000001}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected levels: got: %v want: %v", got, want)
+	}
+	if got, _ := parseLevels("", 2); !reflect.DeepEqual(got, []int{101, 1001}) {
+		t.Errorf("unexpected default levels: got: %v want: %v", got, []int{101, 1001})
+	}
+	for _, bad := range []string{"0", "18", "x"} {
+		if _, err := parseLevels(bad, 2); err == nil {
+			t.Errorf("for %q: got no error", bad)
+		}
+	}
+}
+
+func TestMakeCases(t *testing.T) {
+	f, err := newCaseFilter("^Erat[45]$", "IsPrime")
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	var got []string
+	for _, c := range makeCases([]int{101}, []int{1234}, f) {
+		got = append(got, c.name())
+	}
+	want := []string{
+		"IsPrime: Erat4(101)", "IsPrime: Erat4(1234)",
+		"IsPrime: Erat5(101)", "IsPrime: Erat5(1234)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected cases: got: %v want: %v", got, want)
+	}
+
+	if _, err := newCaseFilter("", "IsPrime,Factor"); err == nil {
+		t.Errorf("got no error for unknown operation")
+	}
+}
+
+func TestColdWarmCases(t *testing.T) {
+	f, err := newCaseFilter("^Memo$", "IsPrime")
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	cases := makeCases(nil, []int{1009}, f)
+	if len(cases) != 2 || cases[0].impl != "Memo/cold" || cases[1].impl != "Memo" {
+		t.Fatalf("unexpected cases: %+v", cases)
+	}
+	cold, warm := cases[0], cases[1]
+	if cold.setup == nil || warm.setup != nil {
+		t.Errorf("want setup for only the cold case")
+	}
+	cold.setup()
+	if got := cold.run().String(); got != "true" {
+		t.Errorf("cold IsPrime(1009): got: %s want: true", got)
+	}
+	release := warm.ready()
+	defer release()
+	if got := warm.run().String(); got != "true" {
+		t.Errorf("warm IsPrime(1009): got: %s want: true", got)
+	}
+}
+
+func TestCasesAreLazy(t *testing.T) {
+	// Listing cases at a level no Primer could sieve in time only works if they don't sieve.
+	f, err := newCaseFilter("^(Memo|Erat5)$", "PrimesUpTo,IsPrime")
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	cases := makeCases(levelGen(13)[12:], nil, f)
+	if len(cases) != 6 {
+		t.Fatalf("got %d cases, want 6: %+v", len(cases), cases)
+	}
+	for _, c := range cases {
+		if c.op == "IsPrime" && c.arg != 99999999999973 {
+			t.Errorf("%s: want the largest prime up to %d, 99999999999973", c.name(), levelGen(13)[12])
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/cceckman/primes/results"
+)
+
+// delta is the change in a case's performance between a baseline run and this one.
+type delta struct {
+	old, new results.Record
+}
+
+// change returns the change in time per operation, as a percentage of the baseline.
+func (d delta) change() float64 {
+	if d.old.NsPerOp == 0 {
+		return 0
+	}
+	return (d.new.NsPerOp - d.old.NsPerOp) / d.old.NsPerOp * 100
+}
+
+// compare joins current against baseline by case, in current's order.
+// Cases that aren't in both are left out.
+func compare(baseline, current []results.Record) []delta {
+	old := make(map[results.Key]results.Record)
+	for _, r := range baseline {
+		old[r.Key()] = r
+	}
+	var deltas []delta
+	for _, r := range current {
+		if o, ok := old[r.Key()]; ok {
+			deltas = append(deltas, delta{old: o, new: r})
+		}
+	}
+	return deltas
+}
+
+// readResults reads the records in the file at path, in any format results.Read understands.
+func readResults(path string) ([]results.Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return results.Read(f)
+}
+
+// printDeltas writes a table of deltas to w, and returns how many of them are regressions:
+// slower than the baseline by more than threshold percent.
+func printDeltas(w io.Writer, deltas []delta, threshold float64) int {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Case\tOld ns/op\tNew ns/op\tDelta\tOld B/op\tNew B/op\t\t")
+	regressions := 0
+	for _, d := range deltas {
+		flag := ""
+		if d.change() > threshold {
+			flag = "REGRESSION"
+			regressions++
+		}
+		fmt.Fprintf(tw, "%s\t%.0f\t%.0f\t%+.1f%%\t%d\t%d\t%s\t\n",
+			d.new.Name(), d.old.NsPerOp, d.new.NsPerOp, d.change(), d.old.BytesPerOp, d.new.BytesPerOp, flag)
+	}
+	tw.Flush()
+	return regressions
+}
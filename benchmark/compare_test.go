@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cceckman/primes/results"
+)
+
+func TestCompare(t *testing.T) {
+	baseline := []results.Record{
+		{Op: "IsPrime", Impl: "Erat2", Arg: 9973, NsPerOp: 1000},
+		{Op: "IsPrime", Impl: "Erat5", Arg: 9973, NsPerOp: 1000},
+		{Op: "IsPrime", Impl: "Gone", Arg: 9973, NsPerOp: 1000},
+	}
+	current := []results.Record{
+		{Op: "IsPrime", Impl: "Erat2", Arg: 9973, NsPerOp: 1200},
+		{Op: "IsPrime", Impl: "Erat5", Arg: 9973, NsPerOp: 900},
+		{Op: "IsPrime", Impl: "New", Arg: 9973, NsPerOp: 1000},
+	}
+
+	deltas := compare(baseline, current)
+	if len(deltas) != 2 {
+		t.Fatalf("unexpected number of deltas: got: %d want: %d", len(deltas), 2)
+	}
+	if got, want := deltas[0].change(), 20.0; got != want {
+		t.Errorf("unexpected change for %s: got: %v want: %v", deltas[0].new.Name(), got, want)
+	}
+
+	var buf bytes.Buffer
+	if got := printDeltas(&buf, deltas, 10); got != 1 {
+		t.Errorf("unexpected number of regressions: got: %d want: %d", got, 1)
+	}
+	if !strings.Contains(buf.String(), "REGRESSION") {
+		t.Errorf("regression not flagged in table:\n%s", buf.String())
+	}
+	if got := printDeltas(&buf, deltas, 25); got != 0 {
+		t.Errorf("unexpected number of regressions: got: %d want: %d", got, 0)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/cceckman/primes/results"
+)
+
+// errPerfUnsupported is returned by openPerfCounters where there's no perf_event.
+var errPerfUnsupported = errors.New("hardware counters are only supported on Linux")
+
+// perfWarned records whether we've said that hardware counters are unavailable, so we only say so once.
+var perfWarned = false
+
+// primesPkg prefixes the names of the functions in the primes package (but not its subpackages).
+const primesPkg = "github.com/cceckman/primes."
+
+// caseFunc prefixes the names of the closures that run cases, made by opCase. (It's "main.opCase.",
+// except in tests.)
+var caseFunc = runtime.FuncForPC(reflect.ValueOf(opCase).Pointer()).Name() + "."
+
+// measureDetail fills in the parts of d that take extra, untimed calls to c: the allocation
+// breakdown, and hardware counters if they're available. calls is how many calls to count
+// hardware events over.
+func measureDetail(c benchCase, calls int, d *results.Detail) {
+	d.SieveBytesPerOp, d.OtherBytesPerOp = allocBreakdown(c)
+
+	counters, err := openPerfCounters()
+	if err != nil {
+		if !perfWarned {
+			fmt.Fprintf(os.Stderr, "not reporting hardware counters: %v\n", err)
+			perfWarned = true
+		}
+		return
+	}
+	defer counters.close()
+	for i := 0; i < calls; i++ {
+		if c.setup != nil {
+			c.setup()
+		}
+		counters.enable()
+		c.run()
+		counters.disable()
+	}
+	instructions, cacheMisses, err := counters.read()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reading hardware counters: %v\n", err)
+		return
+	}
+	d.InstructionsPerOp = float64(instructions) / float64(calls)
+	d.CacheMissesPerOp = float64(cacheMisses) / float64(calls)
+}
+
+// allocBreakdown returns how many bytes a call to c allocates in the primes package, and how
+// many elsewhere in the call: the output channel, the goroutine feeding it, the case's closures.
+// It profiles every allocation of the call, by the stack that made it; allocations by anything
+// else in the meantime, like the profiling itself, are ignored.
+func allocBreakdown(c benchCase) (sieve, other int64) {
+	defer func(rate int) { runtime.MemProfileRate = rate }(runtime.MemProfileRate)
+	runtime.MemProfileRate = 1
+
+	if c.setup != nil {
+		c.setup()
+	}
+	before := allocsByStack()
+	c.run()
+	for stack, bytes := range allocsByStack() {
+		bytes -= before[stack]
+		if bytes == 0 {
+			continue
+		}
+		switch {
+		case calls(stack, primesPkg):
+			sieve += bytes
+		case calls(stack, caseFunc):
+			other += bytes
+		}
+	}
+	return sieve, other
+}
+
+// allocsByStack returns the bytes allocated so far, by the stack that allocated them.
+func allocsByStack() map[[32]uintptr]int64 {
+	// The profile is as of the last GC; run one to bring it up to date.
+	runtime.GC()
+	var records []runtime.MemProfileRecord
+	n, ok := runtime.MemProfile(nil, true)
+	for !ok {
+		// Leave room for the profile to grow in between.
+		records = make([]runtime.MemProfileRecord, n+50)
+		n, ok = runtime.MemProfile(records, true)
+	}
+	m := make(map[[32]uintptr]int64, n)
+	for _, r := range records[:n] {
+		m[r.Stack0] += r.AllocBytes
+	}
+	return m
+}
+
+// calls returns whether any of stack's frames are in a function whose name starts with prefix.
+func calls(stack [32]uintptr, prefix string) bool {
+	pcs := stack[:]
+	for i, pc := range pcs {
+		if pc == 0 {
+			pcs = pcs[:i]
+			break
+		}
+	}
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		if strings.HasPrefix(f.Function, prefix) {
+			return true
+		}
+		if !more {
+			return false
+		}
+	}
+}
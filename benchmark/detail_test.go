@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/cceckman/primes/results"
+)
+
+func TestAllocBreakdown(t *testing.T) {
+	f, err := newCaseFilter("^Erat5$", "PrimesUpTo")
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	cases := makeCases(nil, []int{100001}, f)
+	if len(cases) != 1 {
+		t.Fatalf("unexpected cases: %+v", cases)
+	}
+	defer cases[0].ready()()
+	sieve, other := allocBreakdown(cases[0])
+	// Erat5 sieves the odd numbers, a byte each.
+	if sieve < 100001/2 {
+		t.Errorf("sieve allocations: got: %d want at least: %d", sieve, 100001/2)
+	}
+	if other <= 0 || other >= sieve {
+		t.Errorf("other allocations: got: %d want more than 0 but less than sieve allocations (%d)", other, sieve)
+	}
+}
+
+func TestMeasureDetail(t *testing.T) {
+	f, err := newCaseFilter("^Memo$", "IsPrime")
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	// Whether or not there are hardware counters here, this should fill in what it can.
+	for _, c := range makeCases(nil, []int{1009}, f) {
+		var d results.Detail
+		release := c.ready()
+		measureDetail(c, 10, &d)
+		release()
+		if d.InstructionsPerOp < 0 || d.CacheMissesPerOp < 0 {
+			t.Errorf("%s: unexpected counters: %+v", c.name(), d)
+		}
+		if c.impl == "Memo/cold" && d.SieveBytesPerOp == 0 {
+			t.Errorf("%s: got no sieve allocations", c.name())
+		}
+	}
+}
@@ -3,88 +3,205 @@ package main
 import (
 	"flag"
 	"fmt"
-	"github.com/cceckman/primes"
-	"github.com/cceckman/bencher"
+	"github.com/cceckman/primes/results"
 	"os"
+	"runtime/trace"
+	"time"
 )
 
 var (
-	maxLevel = flag.Int("max_level", 5, "How far to run benchmarks: up to 1..1 with this many zeros in the middle.")
-	help = flag.Bool("help", false, "Display a usage message.")
+	maxLevel  = flag.Int("max_level", 5, "How far to run benchmarks: up to 1..1 with this many zeros in the middle.")
+	format    = flag.String("format", "tsv", "Output format: json, csv, tsv, or benchstat (the output of `go test -bench`).")
+	samples   = flag.Int("samples", 1, "How many independent samples to take of each case. With more than one, report statistics across them.")
+	warmup    = flag.Int("warmup", 1, "How many untimed calls to make to each case before sampling it.")
+	baseline  = flag.String("baseline", "", "A results file (e.g. results.tsv) to compare this run against.")
+	threshold = flag.Float64("threshold", 10, "With --baseline: how much slower, in percent, a case may get before it's a regression.")
+	impl      = flag.String("impl", "", "A regular expression; only benchmark the implementations whose names it matches.")
+	ops       = flag.String("op", "PrimesUpTo,IsPrime", "Comma-separated operations to benchmark.")
+	levels    = flag.String("levels", "", "Comma-separated levels to benchmark, e.g. 3,5,7 for 10001, 1000001, 100000001. Overrides --max_level.")
+	args      = flag.String("n", "", "Comma-separated arguments to benchmark each operation on, in addition to the levels.")
+	list      = flag.Bool("list", false, "List the cases that would be run, and exit.")
+
+	cpuProfileDir = flag.String("cpuprofile-dir", "", "If set, write a CPU profile of each case to this directory, e.g. IsPrime-Erat5-9973.cpu.pprof.")
+	memProfileDir = flag.String("memprofile-dir", "", "If set, write a heap profile of each case to this directory, e.g. IsPrime-Erat5-9973.mem.pprof.")
+	counters      = flag.Bool("counters", false, "Also break down each case's memory by whether the Primer or the harness allocated it and, where perf_event is available, count cache misses and instructions. This takes extra calls.")
+	traceFile     = flag.String("trace", "", "If set, write an execution trace of the run to this file, with a task for each case.")
+
+	throughputMode = flag.Bool("throughput", false, "Instead of timing single calls, measure throughput and latency with concurrent callers.")
+	goroutines     = flag.String("goroutines", "", "With --throughput: comma-separated numbers of concurrent callers. Defaults to powers of 2 up to GOMAXPROCS.")
+	duration       = flag.Duration("duration", 2*time.Second, "With --throughput: how long to drive each implementation, at each number of callers.")
+	mix            = flag.Float64("mix", 0.9, "With --throughput: the fraction of calls that are IsPrime; the rest are PrimesUpTo.")
+	maxN           = flag.Int("max_n", 100001, "With --throughput: the largest argument to call with.")
+	dist           = flag.String("dist", "uniform", "With --throughput: how to draw arguments from [2, max_n]: uniform, or log (uniform on a log scale).")
+	seed           = flag.Int64("seed", 1, "With --throughput: the random seed for drawing arguments.")
+	help           = flag.Bool("help", false, "Display a usage message.")
 )
 
 type printInt int
+
 func (p printInt) String() string {
 	return fmt.Sprintf("%d", int(p))
 }
 
 type printBool bool
+
 func (p printBool) String() string {
 	return fmt.Sprintf("%t", bool(p))
 }
 
+// benchCase is a single benchmark: one operation, of one implementation, on one argument.
+type benchCase struct {
+	op   string
+	impl string
+	arg  int
+	run  func() fmt.Stringer
+	// setup, if set, is called before each call to run, untimed.
+	setup func()
+	// prepare, if set, is called once before the case runs (and isn't, if it doesn't). It returns
+	// a function to call once the case is done, to release what it prepared.
+	prepare func() (release func())
+}
+
+// ready prepares c to run, if it needs to, and returns the function that releases it.
+func (c benchCase) ready() (release func()) {
+	if c.prepare == nil {
+		return func() {}
+	}
+	return c.prepare()
+}
+
+func (c benchCase) name() string {
+	return fmt.Sprintf("%s: %s(%d)", c.op, c.impl, c.arg)
+}
+
 // Benchmark the performance of different prime algorithms.
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "report" {
+		runReport(os.Args[2:])
+		return
+	}
+
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "%s: Benchmark performance of prime algorithms.\nUsage:\n", os.Args[0])
 		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nTo chart results, see: %s report --help\n", os.Args[0])
 	}
 	flag.Parse()
 	if *help {
 		flag.Usage()
 		os.Exit(-1)
 	}
+	f, err := results.ParseFormat(*format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(-1)
+	}
 
-	levels := levelGen(*maxLevel)
-
-	closures := make(bencher.Cases)
-
-	// benchmark PrimesUpTo, to get a set of primes to use
-	primesForTesting := make(map[int]int)
+	var base []results.Record
+	if *baseline != "" {
+		// Read the baseline up front, so we find out it's bad before spending minutes benchmarking.
+		if base, err = readResults(*baseline); err != nil {
+			fmt.Fprintf(os.Stderr, "reading baseline: %v\n", err)
+			os.Exit(1)
+		}
+	}
 
-	for name, primer := range primes.Implementations {
-		for _, level := range levels {
-			// Override the names, for correctness.
-			primer := primer
-			level := level
+	filter, err := newCaseFilter(*impl, *ops)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(-1)
+	}
+	lv, err := parseLevels(*levels, *maxLevel)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(-1)
+	}
+	extra, err := parseInts(*args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(-1)
+	}
 
-			// PrimesUpTo test
-			caseName := fmt.Sprintf("%s: %s(%d)", "PrimesUpTo", name, level)
-			closure := func() fmt.Stringer {
-				c := make(chan int)
-				go primer.PrimesUpTo(level, c)
-				x := 0
-				for x = range c { }
-				return printInt(x)
-			}
-			closures[caseName] = bencher.Runnable(closure)
+	if *throughputMode {
+		if err := runThroughput(filter); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			flag.Usage()
+			os.Exit(-1)
+		}
+		return
+	}
 
-			// Make sure we have a set of primes to test. But this is still test-construction phase, so it
-			// won't count against the test itself.
-			if _, ok := primesForTesting[level]; !ok {
-				// Need to add primes for the corresponding test.
-				c := make(chan int)
-				go primer.PrimesUpTo(level, c)
-				x := 0
-				for x = range c { }
-				primesForTesting[level] = x
-			}
+	cases := makeCases(lv, extra, filter)
+	if *list {
+		for _, c := range cases {
+			fmt.Println(c.name())
+		}
+		return
+	}
+	for _, dir := range []string{*cpuProfileDir, *memProfileDir} {
+		if dir == "" {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	var traceOut *os.File
+	if *traceFile != "" {
+		if traceOut, err = os.Create(*traceFile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := trace.Start(traceOut); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
 
-			// And test IsPrime, with the max.
+	env := currentEnv()
+	prof := profiler{cpuDir: *cpuProfileDir, memDir: *memProfileDir}
+	records := make([]results.Record, 0, len(cases))
+	for _, c := range cases {
+		fmt.Fprintln(os.Stderr, c.name())
+		// Prepare outside the profile, and release before the next case, so that only one
+		// case's state is held at a time.
+		release := c.ready()
+		err := prof.run(c, func() {
+			records = append(records, runCase(c, env, *samples, *warmup, *counters))
+		})
+		release()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "profiling %s: %v\n", c.name(), err)
+			os.Exit(1)
+		}
+	}
+	if traceOut != nil {
+		// Not deferred: we may os.Exit below, and the trace should be complete by then.
+		trace.Stop()
+		traceOut.Close()
+	}
 
-			arg := primesForTesting[level]
-			caseName = fmt.Sprintf("%s: %s(%d)", "IsPrime", name, arg)
-			closure = func() fmt.Stringer {
-				return printBool(primer.IsPrime(arg))
-			}
-			closures[caseName] = bencher.Runnable(closure)
+	if err := results.Write(os.Stdout, f, records); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
+	for _, pair := range indistinguishable(records) {
+		fmt.Fprintf(os.Stderr, "%s and %s are not significantly different\n", pair[0].Name(), pair[1].Name())
+	}
 
+	if *baseline != "" {
+		// The records are on stdout; keep the comparison separate.
+		if n := printDeltas(os.Stderr, compare(base, records), *threshold); n > 0 {
+			fmt.Fprintf(os.Stderr, "%d case(s) regressed by more than %.1f%%\n", n, *threshold)
+			os.Exit(2)
 		}
 	}
-	// Get a list of primes, for IsPrime tests
-
-	bencher.AutoBenchmark(closures)
 }
 
 // Generate levels of stressyness; how many zeros we want.
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// perfEventAttr is struct perf_event_attr, from linux/perf_event.h, as of PERF_ATTR_SIZE_VER5.
+type perfEventAttr struct {
+	typ              uint32
+	size             uint32
+	config           uint64
+	samplePeriod     uint64
+	sampleType       uint64
+	readFormat       uint64
+	flags            uint64
+	wakeupEvents     uint32
+	bpType           uint32
+	config1          uint64
+	config2          uint64
+	branchSampleType uint64
+	sampleRegsUser   uint64
+	sampleStackUser  uint32
+	clockID          int32
+	sampleRegsIntr   uint64
+	auxWatermark     uint32
+	sampleMaxStack   uint16
+	_                uint16
+}
+
+const (
+	perfTypeHardware        = 0
+	perfCountHWInstructions = 1
+	perfCountHWCacheMisses  = 3
+
+	// Bits of perfEventAttr.flags.
+	perfFlagDisabled      = 1 << 0
+	perfFlagExcludeKernel = 1 << 5
+	perfFlagExcludeHV     = 1 << 6
+
+	perfFlagFDCloexec = 1 << 3
+
+	perfIocEnable  = 0x2400
+	perfIocDisable = 0x2401
+)
+
+// perfCounters counts instructions and cache misses in user space, across the threads of this
+// process that exist when it's opened. (The Go runtime rarely starts new threads once it's
+// warmed up; what happens on any that it does start isn't counted.)
+type perfCounters struct {
+	instructions, cacheMisses []int
+}
+
+// openPerfCounters opens disabled counters on each of the process's threads.
+func openPerfCounters() (*perfCounters, error) {
+	tasks, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return nil, err
+	}
+	p := &perfCounters{}
+	for _, task := range tasks {
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil {
+			continue
+		}
+		ins, err := perfEventOpen(perfCountHWInstructions, tid)
+		if err == nil {
+			p.instructions = append(p.instructions, ins)
+		}
+		misses, err2 := perfEventOpen(perfCountHWCacheMisses, tid)
+		if err2 == nil {
+			p.cacheMisses = append(p.cacheMisses, misses)
+		}
+		if err == syscall.ESRCH || err2 == syscall.ESRCH {
+			// The thread exited in the meantime; no matter.
+			continue
+		}
+		if err != nil || err2 != nil {
+			p.close()
+			if err == nil {
+				err = err2
+			}
+			return nil, os.NewSyscallError("perf_event_open", err)
+		}
+	}
+	return p, nil
+}
+
+func perfEventOpen(config uint64, tid int) (int, error) {
+	attr := perfEventAttr{
+		typ:    perfTypeHardware,
+		config: config,
+		flags:  perfFlagDisabled | perfFlagExcludeKernel | perfFlagExcludeHV,
+	}
+	attr.size = uint32(unsafe.Sizeof(attr))
+	cpu := -1 // any CPU
+	group := -1
+	fd, _, errno := syscall.Syscall6(syscall.SYS_PERF_EVENT_OPEN,
+		uintptr(unsafe.Pointer(&attr)), uintptr(tid), uintptr(cpu), uintptr(group), perfFlagFDCloexec, 0)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+func (p *perfCounters) all() []int {
+	return append(append([]int(nil), p.instructions...), p.cacheMisses...)
+}
+
+func (p *perfCounters) ioctl(req uintptr) {
+	for _, fd := range p.all() {
+		syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), req, 0)
+	}
+}
+
+func (p *perfCounters) enable()  { p.ioctl(perfIocEnable) }
+func (p *perfCounters) disable() { p.ioctl(perfIocDisable) }
+
+// read returns the counts so far, summed across threads.
+func (p *perfCounters) read() (instructions, cacheMisses uint64, err error) {
+	sum := func(fds []int) uint64 {
+		var total uint64
+		var buf [8]byte
+		for _, fd := range fds {
+			if _, e := syscall.Read(fd, buf[:]); e != nil {
+				err = e
+				continue
+			}
+			total += binary.NativeEndian.Uint64(buf[:])
+		}
+		return total
+	}
+	instructions, cacheMisses = sum(p.instructions), sum(p.cacheMisses)
+	return instructions, cacheMisses, err
+}
+
+func (p *perfCounters) close() {
+	for _, fd := range p.all() {
+		syscall.Close(fd)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package main
+
+// perfCounters is a stand-in for hardware counters, which we don't have here.
+type perfCounters struct{}
+
+func openPerfCounters() (*perfCounters, error) {
+	return nil, errPerfUnsupported
+}
+
+func (*perfCounters) enable()  {}
+func (*perfCounters) disable() {}
+func (*perfCounters) read() (instructions, cacheMisses uint64, err error) {
+	return 0, 0, errPerfUnsupported
+}
+func (*perfCounters) close() {}
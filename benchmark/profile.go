@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"strings"
+)
+
+// profiler captures profiles of each case it runs, into files named after the case.
+type profiler struct {
+	// cpuDir and memDir are where to write CPU and heap profiles; empty means don't.
+	cpuDir, memDir string
+}
+
+// fileName returns a name for c's files, e.g. "IsPrime-Memo-cold-9973".
+func (c benchCase) fileName() string {
+	return fmt.Sprintf("%s-%s-%d", c.op, strings.ReplaceAll(c.impl, "/", "-"), c.arg)
+}
+
+// run calls f, which benchmarks c, capturing the profiles p asks for. If an execution trace is
+// running, f is marked in it as a task named after c.
+//
+// Heap profiles count allocations since the program started, so each case also gets a ".base"
+// profile taken just before it; `go tool pprof -base X.mem.base.pprof X.mem.pprof` shows the
+// allocations of the case alone.
+func (p profiler) run(c benchCase, f func()) error {
+	if p.memDir != "" {
+		if err := writeHeapProfile(filepath.Join(p.memDir, c.fileName()+".mem.base.pprof")); err != nil {
+			return err
+		}
+	}
+	if p.cpuDir != "" {
+		out, err := os.Create(filepath.Join(p.cpuDir, c.fileName()+".cpu.pprof"))
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		if err := pprof.StartCPUProfile(out); err != nil {
+			return err
+		}
+	}
+
+	ctx, task := trace.NewTask(context.Background(), c.name())
+	trace.WithRegion(ctx, "benchmark", f)
+	task.End()
+
+	if p.cpuDir != "" {
+		pprof.StopCPUProfile()
+	}
+	if p.memDir != "" {
+		return writeHeapProfile(filepath.Join(p.memDir, c.fileName()+".mem.pprof"))
+	}
+	return nil
+}
+
+// writeHeapProfile writes a heap profile, up to date as of now, to path.
+func writeHeapProfile(path string) error {
+	// The profile's allocation counts are as of the last GC; run one to bring them up to date.
+	runtime.GC()
+	var b bytes.Buffer
+	if err := pprof.Lookup("allocs").WriteTo(&b, 0); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b.Bytes(), 0644)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProfiler(t *testing.T) {
+	dir := t.TempDir()
+	c := benchCase{op: "IsPrime", impl: "Memo/cold", arg: 101}
+	ran := false
+	p := profiler{cpuDir: dir, memDir: dir}
+	if err := p.run(c, func() { ran = true }); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if !ran {
+		t.Errorf("case didn't run")
+	}
+	for _, suffix := range []string{".cpu.pprof", ".mem.pprof", ".mem.base.pprof"} {
+		name := filepath.Join(dir, fmt.Sprintf("IsPrime-Memo-cold-101%s", suffix))
+		if fi, err := os.Stat(name); err != nil || fi.Size() == 0 {
+			t.Errorf("missing or empty profile %s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cceckman/primes/results"
+)
+
+// Chart geometry, in SVG user units.
+const (
+	chartWidth   = 640
+	chartHeight  = 400
+	chartMargin  = 60 // room for the axis labels
+	legendHeight = 20 // per legend line
+)
+
+// palette colors the implementations' curves; it's cycled through if there are more of them.
+var palette = []string{
+	"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd",
+	"#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf",
+}
+
+// metric is a quantity to chart, as a function of the case's argument.
+type metric struct {
+	title string
+	unit  string
+	value func(results.Record) float64
+}
+
+var metrics = []metric{
+	{"Time", "ns/op", func(r results.Record) float64 { return r.NsPerOp }},
+	{"Memory", "B/op", func(r results.Record) float64 { return float64(r.BytesPerOp) }},
+}
+
+// point is a point on a curve; a curve is one implementation's results for one operation.
+type point struct {
+	x, y float64
+}
+
+type curve struct {
+	impl   string
+	points []point
+}
+
+// exponent returns the slope of the least-squares fit of the curve on a log-log scale: the k in
+// y ~ x^k. It returns NaN if there are fewer than two distinct points to fit.
+func (c curve) exponent() float64 {
+	n := float64(len(c.points))
+	var sx, sy, sxx, sxy float64
+	for _, p := range c.points {
+		x, y := math.Log(p.x), math.Log(p.y)
+		sx += x
+		sy += y
+		sxx += x * x
+		sxy += x * y
+	}
+	d := n*sxx - sx*sx
+	if n < 2 || d == 0 {
+		return math.NaN()
+	}
+	return (n*sxy - sx*sy) / d
+}
+
+// runReport implements `benchmark report`: it reads result files and renders them as charts.
+func runReport(args []string) {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	out := fs.String("out", "", "Where to write the HTML report. Defaults to stdout.")
+	svgDir := fs.String("svg_dir", "", "If set, also write each chart to its own SVG file in this directory.")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "%s report: Chart benchmark results against n.\nUsage: %s report [flags] FILE...\n", os.Args[0], os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(-1)
+	}
+
+	var records []results.Record
+	for _, path := range fs.Args() {
+		rs, err := readResults(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "reading %s: %v\n", path, err)
+			os.Exit(1)
+		}
+		records = append(records, rs...)
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := writeReport(w, records, *svgDir); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// curves groups records by operation, then by implementation, into curves of the given metric.
+// Later records for the same case replace earlier ones. Points that can't go on a log scale
+// (i.e. zero) are left out.
+func curves(records []results.Record, m metric) map[string][]curve {
+	latest := make(map[results.Key]results.Record)
+	for _, r := range records {
+		latest[r.Key()] = r
+	}
+	byOp := make(map[string]map[string][]point)
+	for k, r := range latest {
+		y := m.value(r)
+		if k.Arg <= 0 || y <= 0 {
+			continue
+		}
+		if byOp[k.Op] == nil {
+			byOp[k.Op] = make(map[string][]point)
+		}
+		byOp[k.Op][k.Impl] = append(byOp[k.Op][k.Impl], point{float64(k.Arg), y})
+	}
+
+	result := make(map[string][]curve)
+	for op, impls := range byOp {
+		for impl, points := range impls {
+			sort.Slice(points, func(i, j int) bool { return points[i].x < points[j].x })
+			result[op] = append(result[op], curve{impl: impl, points: points})
+		}
+		sort.Slice(result[op], func(i, j int) bool { return result[op][i].impl < result[op][j].impl })
+	}
+	return result
+}
+
+// writeReport writes an HTML page with a chart for each operation and metric, and a table of
+// the fitted exponents. If svgDir is set, it also writes each chart there as a standalone SVG.
+func writeReport(w io.Writer, records []results.Record, svgDir string) error {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>primes benchmarks</title>\n")
+	b.WriteString("<style>body{font-family:sans-serif} table{border-collapse:collapse} td,th{border:1px solid #ccc;padding:2px 8px;text-align:right}</style>\n")
+	b.WriteString("</head><body>\n<h1>primes benchmarks</h1>\n")
+
+	for _, m := range metrics {
+		byOp := curves(records, m)
+		ops := make([]string, 0, len(byOp))
+		for op := range byOp {
+			ops = append(ops, op)
+		}
+		sort.Strings(ops)
+
+		for _, op := range ops {
+			title := fmt.Sprintf("%s: %s (%s) vs. n", op, m.title, m.unit)
+			svg := renderChart(title, m.unit, byOp[op])
+			fmt.Fprintf(&b, "<h2>%s</h2>\n%s\n", html.EscapeString(title), svg)
+
+			b.WriteString("<table><tr><th>Implementation</th><th>Fitted exponent</th></tr>\n")
+			for _, c := range byOp[op] {
+				fmt.Fprintf(&b, "<tr><td>%s</td><td>%.3f</td></tr>\n", html.EscapeString(c.impl), c.exponent())
+			}
+			b.WriteString("</table>\n")
+
+			if svgDir != "" {
+				name := filepath.Join(svgDir, fmt.Sprintf("%s-%s.svg", op, strings.ToLower(m.title)))
+				if err := os.WriteFile(name, []byte(svg), 0644); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	b.WriteString("</body></html>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// renderChart renders curves as a standalone log-log SVG line chart.
+func renderChart(title, unit string, curves []curve) string {
+	// Find the bounds, rounded out to powers of 10.
+	minX, maxX, minY, maxY := math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+	for _, c := range curves {
+		for _, p := range c.points {
+			minX, maxX = math.Min(minX, p.x), math.Max(maxX, p.x)
+			minY, maxY = math.Min(minY, p.y), math.Max(maxY, p.y)
+		}
+	}
+	x0, x1 := math.Floor(math.Log10(minX)), math.Ceil(math.Log10(maxX))
+	y0, y1 := math.Floor(math.Log10(minY)), math.Ceil(math.Log10(maxY))
+	if x1 == x0 {
+		x1++
+	}
+	if y1 == y0 {
+		y1++
+	}
+
+	plotW, plotH := float64(chartWidth-2*chartMargin), float64(chartHeight-2*chartMargin)
+	sx := func(x float64) float64 { return chartMargin + (math.Log10(x)-x0)/(x1-x0)*plotW }
+	sy := func(y float64) float64 { return chartMargin + plotH - (math.Log10(y)-y0)/(y1-y0)*plotH }
+
+	height := chartHeight + legendHeight*len(curves)
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n",
+		chartWidth, height, chartWidth, height)
+	fmt.Fprintf(&b, `<title>%s</title>`+"\n", html.EscapeString(title))
+	fmt.Fprintf(&b, `<rect x="0" y="0" width="%d" height="%d" fill="white"/>`+"\n", chartWidth, height)
+
+	// Grid lines and labels at each power of 10.
+	for e := x0; e <= x1; e++ {
+		x := sx(math.Pow(10, e))
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%.1f" stroke="#ddd"/>`+"\n", x, chartMargin, x, chartMargin+plotH)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle">1e%d</text>`+"\n", x, chartMargin+plotH+16, int(e))
+	}
+	for e := y0; e <= y1; e++ {
+		y := sy(math.Pow(10, e))
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#ddd"/>`+"\n", chartMargin, y, chartMargin+plotW, y)
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end">1e%d</text>`+"\n", chartMargin-4, y+4, int(e))
+	}
+	fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%.1f" height="%.1f" fill="none" stroke="black"/>`+"\n", chartMargin, chartMargin, plotW, plotH)
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle" font-size="14">%s</text>`+"\n", chartWidth/2, chartMargin/2, html.EscapeString(title))
+	fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="middle">n</text>`+"\n", chartWidth/2, chartMargin+plotH+36)
+	fmt.Fprintf(&b, `<text x="16" y="%d" text-anchor="middle" transform="rotate(-90 16 %d)">%s</text>`+"\n", chartHeight/2, chartHeight/2, html.EscapeString(unit))
+
+	for i, c := range curves {
+		color := palette[i%len(palette)]
+		pts := make([]string, len(c.points))
+		for j, p := range c.points {
+			pts[j] = fmt.Sprintf("%.1f,%.1f", sx(p.x), sy(p.y))
+			fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s"/>`+"\n", sx(p.x), sy(p.y), color)
+		}
+		fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`+"\n", strings.Join(pts, " "), color)
+
+		// Legend, below the chart.
+		ly := chartHeight + legendHeight*i
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="12" height="12" fill="%s"/>`+"\n", chartMargin, ly, color)
+		fmt.Fprintf(&b, `<text x="%d" y="%d">%s (exponent %.2f)</text>`+"\n", chartMargin+18, ly+11, html.EscapeString(c.impl), c.exponent())
+	}
+	b.WriteString("</svg>")
+	return b.String()
+}
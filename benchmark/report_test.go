@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/cceckman/primes/results"
+)
+
+func TestExponent(t *testing.T) {
+	c := curve{points: []point{{10, 300}, {100, 30000}, {1000, 3000000}}}
+	if got := c.exponent(); math.Abs(got-2) > 1e-9 {
+		t.Errorf("unexpected exponent: got: %v want: %v", got, 2)
+	}
+	if got := (curve{points: []point{{10, 300}}}).exponent(); !math.IsNaN(got) {
+		t.Errorf("unexpected exponent for a single point: got: %v want: NaN", got)
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	var records []results.Record
+	for _, n := range levelGen(3) {
+		records = append(records,
+			results.Record{Op: "PrimesUpTo", Impl: "Erat5", Arg: n, NsPerOp: float64(n) * 10, BytesPerOp: int64(n / 2)},
+			results.Record{Op: "PrimesUpTo", Impl: "<Memo>", Arg: n, NsPerOp: 300},
+		)
+	}
+
+	var buf bytes.Buffer
+	if err := writeReport(&buf, records, ""); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	got := buf.String()
+	for _, want := range []string{"<svg", "PrimesUpTo: Time (ns/op) vs. n", "PrimesUpTo: Memory (B/op) vs. n", "&lt;Memo&gt;", "1.000"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report does not contain %q", want)
+		}
+	}
+	// It has to work offline.
+	if strings.Contains(got, "<script") || strings.Contains(got, "https://") {
+		t.Errorf("report is not self-contained")
+	}
+}
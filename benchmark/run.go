@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cceckman/primes/results"
+)
+
+// runCase benchmarks c, in the style of `go test -bench`: first calling it `warmup` times,
+// untimed, then taking `samples` independent samples. If c has a setup function, it's called
+// before every call to run, with the timer stopped. If detail is set, it also makes extra calls
+// to fill in the rest of the record's Detail; see measureDetail.
+func runCase(c benchCase, env results.Env, samples, warmup int, detail bool) results.Record {
+	result := ""
+	for i := 0; i < warmup; i++ {
+		if c.setup != nil {
+			c.setup()
+		}
+		result = c.run().String()
+	}
+
+	r := results.Record{
+		Op:   c.op,
+		Impl: c.impl,
+		Arg:  c.arg,
+		Env:  env,
+	}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for i := 0; i < samples; i++ {
+		res := testing.Benchmark(func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if c.setup != nil {
+					b.StopTimer()
+					c.setup()
+					b.StartTimer()
+				}
+				result = c.run().String()
+			}
+		})
+		if res.N == 0 {
+			// The benchmark failed; there's nothing to record.
+			continue
+		}
+		r.Samples = append(r.Samples, results.Sample{
+			Iterations:  res.N,
+			NsPerOp:     float64(res.T.Nanoseconds()) / float64(res.N),
+			BytesPerOp:  res.AllocedBytesPerOp(),
+			AllocsPerOp: res.AllocsPerOp(),
+		})
+	}
+	runtime.ReadMemStats(&after)
+	r.Result = result
+	r.Detail.GCs = int64(after.NumGC - before.NumGC)
+	r.Detail.GCPauseNs = int64(after.PauseTotalNs - before.PauseTotalNs)
+
+	r.Stats, r.NsPerOp = results.Summarize(r.Samples)
+	if n := len(r.Samples); n > 0 {
+		last := r.Samples[n-1]
+		r.Iterations, r.BytesPerOp, r.AllocsPerOp = last.Iterations, last.BytesPerOp, last.AllocsPerOp
+	}
+	if detail {
+		// Count hardware events over about a tenth as many calls as the last sample timed.
+		measureDetail(c, r.Iterations/10+1, &r.Detail)
+	}
+	if len(r.Samples) == 1 {
+		// The summary says it all.
+		r.Samples = nil
+	}
+	return r
+}
+
+// indistinguishable returns the pairs of records for the same operation and argument, but
+// different implementations, whose times are not significantly different: that is, whose 95%
+// confidence intervals overlap. It only considers records with more than one sample.
+func indistinguishable(records []results.Record) [][2]results.Record {
+	var pairs [][2]results.Record
+	for i, a := range records {
+		if a.Stats.Samples < 2 {
+			continue
+		}
+		for _, b := range records[i+1:] {
+			if b.Stats.Samples < 2 || a.Op != b.Op || a.Arg != b.Arg || a.Impl == b.Impl {
+				continue
+			}
+			if a.Stats.Overlaps(b.Stats) {
+				pairs = append(pairs, [2]results.Record{a, b})
+			}
+		}
+	}
+	return pairs
+}
+
+// currentEnv describes the environment we're running in.
+func currentEnv() results.Env {
+	return results.Env{
+		GoVersion:  runtime.Version(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		CPU:        cpuModel(),
+		Timestamp:  time.Now().UTC(),
+	}
+}
+
+// cpuModel returns the model name of the CPU, if the platform tells us; otherwise, the architecture.
+func cpuModel() string {
+	f, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return runtime.GOARCH
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if k, v, ok := strings.Cut(s.Text(), ":"); ok && strings.TrimSpace(k) == "model name" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return runtime.GOARCH
+}
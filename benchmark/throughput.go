@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cceckman/primes"
+)
+
+// workload describes the queries a throughput run makes.
+type workload struct {
+	// isPrimeFraction is the fraction of queries that are IsPrime; the rest are PrimesUpTo.
+	isPrimeFraction float64
+	// maxN bounds the queries' arguments, which are drawn from [2, maxN].
+	maxN int
+	// logScale draws arguments uniformly on a log scale, rather than uniformly.
+	logScale bool
+	seed     int64
+}
+
+// next returns the next argument to query.
+func (w workload) next(r *rand.Rand) int {
+	if w.logScale {
+		return int(math.Exp(r.Float64()*math.Log(float64(w.maxN)/2) + math.Log(2)))
+	}
+	return 2 + r.Intn(w.maxN-1)
+}
+
+// throughput is the result of driving one Primer from some number of goroutines at once.
+type throughput struct {
+	impl       string
+	goroutines int
+	ops        int
+	elapsed    time.Duration
+	p50, p99   time.Duration
+}
+
+func (t throughput) opsPerSec() float64 {
+	return float64(t.ops) / t.elapsed.Seconds()
+}
+
+// defaultGoroutines returns the goroutine counts to try: powers of 2 up to GOMAXPROCS,
+// and GOMAXPROCS itself.
+func defaultGoroutines() []int {
+	procs := runtime.GOMAXPROCS(0)
+	var gs []int
+	for g := 1; g < procs; g *= 2 {
+		gs = append(gs, g)
+	}
+	return append(gs, procs)
+}
+
+// parseGoroutines parses --goroutines: comma-separated counts of concurrent callers, each at
+// least 1. If s is empty, it returns defaultGoroutines.
+func parseGoroutines(s string) ([]int, error) {
+	gs, err := parseInts(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(gs) == 0 {
+		return defaultGoroutines(), nil
+	}
+	for _, g := range gs {
+		if g < 1 {
+			return nil, fmt.Errorf("bad --goroutines %q: want counts of at least 1", s)
+		}
+	}
+	return gs, nil
+}
+
+// warmInstance returns a new instance from factory. If it's stateful, it first works out
+// everything w can ask for, so that a run measures contention rather than cache misses.
+func warmInstance(factory primes.Factory, stateful bool, w workload) primes.Primer {
+	p := factory()
+	if stateful {
+		c := make(chan int)
+		go p.PrimesUpTo(w.maxN, c)
+		for range c {
+		}
+	}
+	return p
+}
+
+// measureThroughput drives p from g goroutines, each making queries drawn from w, for d.
+func measureThroughput(impl string, p primes.Primer, g int, w workload, d time.Duration) throughput {
+	var wg sync.WaitGroup
+	latencies := make([][]time.Duration, g)
+	deadline := time.Now().Add(d)
+	start := time.Now()
+	for i := 0; i < g; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(w.seed + int64(i)))
+			for time.Now().Before(deadline) {
+				n := w.next(r)
+				isPrime := r.Float64() < w.isPrimeFraction
+				t0 := time.Now()
+				if isPrime {
+					p.IsPrime(n)
+				} else {
+					c := make(chan int)
+					go p.PrimesUpTo(n, c)
+					for _ = range c {
+					}
+				}
+				latencies[i] = append(latencies[i], time.Since(t0))
+			}
+		}()
+	}
+	wg.Wait()
+
+	var all []time.Duration
+	for _, l := range latencies {
+		all = append(all, l...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+	return throughput{
+		impl:       impl,
+		goroutines: g,
+		ops:        len(all),
+		elapsed:    time.Since(start),
+		p50:        percentile(all, 50),
+		p99:        percentile(all, 99),
+	}
+}
+
+// percentile returns the p'th percentile of sorted, by the nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// printThroughput writes a table of results to w.
+func printThroughput(w io.Writer, ts []throughput) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Impl\tGoroutines\tOps\tOps/sec\tp50\tp99\t")
+	for _, t := range ts {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%v\t%v\t\n", t.impl, t.goroutines, t.ops, t.opsPerSec(), t.p50, t.p99)
+	}
+	tw.Flush()
+}
+
+// runThroughput implements --throughput: it measures each implementation that f matches,
+// at each number of goroutines.
+func runThroughput(f caseFilter) error {
+	w := workload{
+		isPrimeFraction: *mix,
+		maxN:            *maxN,
+		seed:            *seed,
+	}
+	switch *dist {
+	case "uniform":
+	case "log":
+		w.logScale = true
+	default:
+		return fmt.Errorf("bad --dist %q: want uniform or log", *dist)
+	}
+	if w.maxN < 3 {
+		return fmt.Errorf("bad --max_n %d: want at least 3", w.maxN)
+	}
+	gs, err := parseGoroutines(*goroutines)
+	if err != nil {
+		return err
+	}
+
+	var ts []throughput
+	for _, name := range f.names() {
+		factory, info, _ := primes.Lookup(name)
+		if !info.ThreadSafe {
+			fmt.Fprintf(os.Stderr, "skipping %s: it isn't safe to call concurrently\n", name)
+			continue
+		}
+		for _, g := range gs {
+			// One instance, shared by the goroutines, as a server would. Each g gets its own,
+			// warmed up the same way, so that one run's work doesn't speed up the next.
+			p := warmInstance(factory, info.Stateful, w)
+			fmt.Fprintf(os.Stderr, "%s with %d goroutine(s)\n", name, g)
+			ts = append(ts, measureThroughput(name, p, g, w, *duration))
+		}
+	}
+	printThroughput(os.Stdout, ts)
+	return nil
+}
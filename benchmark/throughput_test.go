@@ -0,0 +1,82 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/cceckman/primes"
+)
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i))
+	}
+	for _, c := range []struct {
+		p    float64
+		want time.Duration
+	}{{50, 50}, {99, 99}, {100, 100}, {0, 1}} {
+		if got := percentile(sorted, c.p); got != c.want {
+			t.Errorf("for p%v: got: %v want: %v", c.p, got, c.want)
+		}
+	}
+}
+
+func TestWorkload(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, w := range []workload{{maxN: 1000}, {maxN: 1000, logScale: true}} {
+		for i := 0; i < 1000; i++ {
+			if n := w.next(r); n < 2 || n > w.maxN {
+				t.Errorf("argument out of range [2, %d]: %d", w.maxN, n)
+			}
+		}
+	}
+}
+
+func TestParseGoroutines(t *testing.T) {
+	if got, err := parseGoroutines("1,4"); err != nil || !reflect.DeepEqual(got, []int{1, 4}) {
+		t.Errorf("for 1,4: got: %v, %v want: [1 4], nil", got, err)
+	}
+	if got, err := parseGoroutines(""); err != nil || !reflect.DeepEqual(got, defaultGoroutines()) {
+		t.Errorf("for the default: got: %v, %v want: %v, nil", got, err, defaultGoroutines())
+	}
+	for _, s := range []string{"0", "2,-1", "x"} {
+		if got, err := parseGoroutines(s); err == nil {
+			t.Errorf("for %q: got: %v want an error", s, got)
+		}
+	}
+}
+
+func TestWarmInstance(t *testing.T) {
+	w := workload{maxN: 100000}
+	factory, info, _ := primes.Lookup("Memo")
+	a, b := warmInstance(factory, info.Stateful, w), warmInstance(factory, info.Stateful, w)
+	if a == b {
+		t.Errorf("got the same instance twice")
+	}
+	// Warmed up, a has already sieved past any argument w draws; a fresh instance hasn't.
+	warm, cold := allocated(func() { a.IsPrime(99991) }), allocated(func() { factory().IsPrime(99991) })
+	if warm >= cold/2 {
+		t.Errorf("IsPrime(99991) allocated %d bytes when warm, %d when cold", warm, cold)
+	}
+}
+
+// allocated returns how many bytes f allocates.
+func allocated(f func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	f()
+	runtime.ReadMemStats(&after)
+	return after.TotalAlloc - before.TotalAlloc
+}
+
+func TestMeasureThroughput(t *testing.T) {
+	w := workload{isPrimeFraction: 0.5, maxN: 1000, seed: 1}
+	got := measureThroughput("Memo", primes.NewMemoizingPrimer(), 2, w, 10*time.Millisecond)
+	if got.ops == 0 || got.p50 > got.p99 {
+		t.Errorf("unexpected throughput: %+v", got)
+	}
+}
@@ -0,0 +1,282 @@
+// Command primes answers questions about prime numbers, from the command line.
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cceckman/primes"
+)
+
+var (
+	implName = flag.String("impl", "Auto", "Which Primer to count and nth with: "+strings.Join(primes.Names(), ", ")+". (list sieves just its range, and check, next and factor use Miller-Rabin, whatever this is.)")
+	format   = flag.String("format", "text", "Output format: text, json (a JSON value per line), or binary (little-endian 64-bit integers).")
+)
+
+const usage = `%s: Answer questions about prime numbers.
+Usage: %s [flags] COMMAND ARGS...
+
+Commands:
+  list LO HI    The primes from LO through HI.
+  count X       How many primes there are up to X.
+  check N...    Whether each N is prime.
+  nth K         The Kth prime; the first is 2.
+  next N        The smallest prime greater than N.
+  factor N...   The prime factors of each N, with multiplicity.
+
+With no N, check and factor read whitespace-separated numbers from stdin,
+and answer each as it arrives.
+
+In binary, numbers are 8 bytes each. check writes a byte per N, 1 if it's
+prime and 0 if not; factor writes, for each N, the number of factors and
+then the factors.
+
+Flags:
+`
+
+// errUsage indicates that the command line was malformed.
+var errUsage = errors.New("bad usage")
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, usage, os.Args[0], os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	err := run(flag.Args(), *implName, *format, os.Stdin, os.Stdout)
+	if errors.Is(err, errUsage) {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[0], err)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[0], err)
+		os.Exit(1)
+	}
+}
+
+// run runs the command in args, using the named implementation, and writes the results to
+// stdout in the named format.
+func run(args []string, impl, format string, stdin io.Reader, stdout io.Writer) error {
+	factory, _, ok := primes.Lookup(impl)
+	if !ok {
+		return fmt.Errorf("%w: unknown implementation %q", errUsage, impl)
+	}
+	p := factory()
+	switch format {
+	case "text", "json", "binary":
+	default:
+		return fmt.Errorf("%w: unknown format %q", errUsage, format)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("%w: no command", errUsage)
+	}
+	w := &writer{format: format, w: bufio.NewWriter(stdout)}
+
+	cmd, args := args[0], args[1:]
+	var err error
+	switch cmd {
+	case "list":
+		err = list(args, w)
+	case "count":
+		err = oneNumber(args, w, func(x int) (int, error) { return primes.Count(x, p) })
+	case "nth":
+		err = oneNumber(args, w, func(k int) (int, error) { return primes.Nth(k, p) })
+	case "next":
+		err = oneNumber(args, w, func(n int) (int, error) {
+			next, ok := primes.NextPrime(n)
+			if !ok {
+				return 0, fmt.Errorf("there's no prime after %d that fits in an int", n)
+			}
+			return next, nil
+		})
+	case "check":
+		// A single n is quickest to test with Miller-Rabin, which Auto uses beyond its small table.
+		auto := primes.Auto()
+		err = eachNumber(args, stdin, w, func(n int) error {
+			return w.check(n, auto.IsPrime(n))
+		})
+	case "factor":
+		err = eachNumber(args, stdin, w, func(n int) error {
+			return w.factors(n, primes.Factor(n))
+		})
+	default:
+		return fmt.Errorf("%w: unknown command %q", errUsage, cmd)
+	}
+	if ferr := w.w.Flush(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+func parseNumber(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("bad number %q: %v", s, err)
+	}
+	return n, nil
+}
+
+// list implements `list LO HI`. It sieves just [LO, HI], so a narrow range is quick however
+// large LO is.
+func list(args []string, w *writer) error {
+	if len(args) != 2 {
+		return fmt.Errorf("%w: list takes two arguments, LO and HI", errUsage)
+	}
+	lo, err := parseNumber(args[0])
+	if err != nil {
+		return err
+	}
+	hi, err := parseNumber(args[1])
+	if err != nil {
+		return err
+	}
+	if err := primes.Check(hi, primes.NewSegmentedErat()); err != nil {
+		return err
+	}
+	for prime := range primes.PrimesBetween(lo, hi) {
+		if err := w.number(prime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// oneNumber implements a command that takes one number and answers with another, by f.
+func oneNumber(args []string, w *writer, f func(int) (int, error)) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: want one argument", errUsage)
+	}
+	n, err := parseNumber(args[0])
+	if err != nil {
+		return err
+	}
+	result, err := f(n)
+	if err != nil {
+		return err
+	}
+	return w.number(result)
+}
+
+// eachNumber calls f on each of args; or, if there are none, on each number read from stdin.
+// It flushes w after each number from stdin, so the answers come as the questions do.
+func eachNumber(args []string, stdin io.Reader, w *writer, f func(int) error) error {
+	if len(args) > 0 {
+		for _, arg := range args {
+			n, err := parseNumber(arg)
+			if err != nil {
+				return err
+			}
+			if err := f(n); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	s := bufio.NewScanner(stdin)
+	s.Split(bufio.ScanWords)
+	for s.Scan() {
+		n, err := parseNumber(s.Text())
+		if err != nil {
+			return err
+		}
+		if err := f(n); err != nil {
+			return err
+		}
+		if err := w.w.Flush(); err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
+
+// writer writes results in one of the output formats.
+type writer struct {
+	format string
+	w      *bufio.Writer
+}
+
+func (w *writer) uint64(n int) error {
+	return binary.Write(w.w, binary.LittleEndian, uint64(n))
+}
+
+func (w *writer) json(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w.w, "%s\n", b)
+	return err
+}
+
+// number writes a number on its own.
+func (w *writer) number(n int) error {
+	switch w.format {
+	case "json":
+		return w.json(n)
+	case "binary":
+		return w.uint64(n)
+	}
+	_, err := fmt.Fprintln(w.w, n)
+	return err
+}
+
+// check writes whether n is prime.
+func (w *writer) check(n int, prime bool) error {
+	switch w.format {
+	case "json":
+		return w.json(struct {
+			N     int  `json:"n"`
+			Prime bool `json:"prime"`
+		}{n, prime})
+	case "binary":
+		b := byte(0)
+		if prime {
+			b = 1
+		}
+		return w.w.WriteByte(b)
+	}
+	_, err := fmt.Fprintf(w.w, "%d: %t\n", n, prime)
+	return err
+}
+
+// factors writes n's factors.
+func (w *writer) factors(n int, factors []int) error {
+	switch w.format {
+	case "json":
+		if factors == nil {
+			factors = []int{}
+		}
+		return w.json(struct {
+			N       int   `json:"n"`
+			Factors []int `json:"factors"`
+		}{n, factors})
+	case "binary":
+		if err := w.uint64(len(factors)); err != nil {
+			return err
+		}
+		for _, f := range factors {
+			if err := w.uint64(f); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// As in coreutils' factor: "12: 2 2 3".
+	fmt.Fprintf(w.w, "%d:", n)
+	for _, f := range factors {
+		fmt.Fprintf(w.w, " %d", f)
+	}
+	_, err := fmt.Fprintln(w.w)
+	return err
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	for _, c := range []struct {
+		args   []string
+		format string
+		stdin  string
+		want   string
+	}{
+		{[]string{"list", "10", "30"}, "text", "", "11\n13\n17\n19\n23\n29\n"},
+		{[]string{"list", "10", "20"}, "json", "", "11\n13\n17\n19\n"},
+		{[]string{"count", "1000"}, "text", "", "168\n"},
+		{[]string{"nth", "168"}, "text", "", "997\n"},
+		{[]string{"next", "997"}, "text", "", "1009\n"},
+		{[]string{"check", "7", "9"}, "text", "", "7: true\n9: false\n"},
+		{[]string{"check"}, "json", "7\n 9 ", `{"n":7,"prime":true}` + "\n" + `{"n":9,"prime":false}` + "\n"},
+		{[]string{"factor", "12", "1"}, "text", "", "12: 2 2 3\n1:\n"},
+		{[]string{"factor"}, "json", "12 97", `{"n":12,"factors":[2,2,3]}` + "\n" + `{"n":97,"factors":[97]}` + "\n"},
+		{[]string{"check", "4", "5"}, "binary", "", "\x00\x01"},
+		// Quick only if they don't sieve from 2.
+		{[]string{"list", "1000000000000", "1000000000100"}, "text", "", "1000000000039\n1000000000061\n1000000000063\n1000000000091\n"},
+		{[]string{"check", "1000000007", "1000000000000000003"}, "text", "", "1000000007: true\n1000000000000000003: true\n"},
+	} {
+		var out bytes.Buffer
+		if err := run(c.args, "Auto", c.format, strings.NewReader(c.stdin), &out); err != nil {
+			t.Errorf("%v in %s: got error: %v", c.args, c.format, err)
+			continue
+		}
+		if got := out.String(); got != c.want {
+			t.Errorf("%v in %s: got: %q want: %q", c.args, c.format, got, c.want)
+		}
+	}
+}
+
+func TestRunBinary(t *testing.T) {
+	var out bytes.Buffer
+	if err := run([]string{"factor", "12"}, "Segmented", "binary", nil, &out); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	// The number of factors, then the factors.
+	want := []uint64{3, 2, 2, 3}
+	if out.Len() != 8*len(want) {
+		t.Fatalf("got %d bytes want: %d", out.Len(), 8*len(want))
+	}
+	got := make([]uint64, len(want))
+	if err := binary.Read(&out, binary.LittleEndian, got); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v want: %v", got, want)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	for _, c := range []struct {
+		args  []string
+		impl  string
+		usage bool
+	}{
+		{nil, "Memo", true},
+		{[]string{"frobnicate"}, "Memo", true},
+		{[]string{"list", "1"}, "Memo", true},
+		{[]string{"count", "10"}, "NoSuchImpl", true},
+		{[]string{"check", "x"}, "Memo", false},
+		{[]string{"count", "1000000000000000"}, "Erat5", false},
+		{[]string{"list", "2", "9000000000000000000"}, "Auto", false},
+	} {
+		err := run(c.args, c.impl, "text", strings.NewReader(""), &bytes.Buffer{})
+		if err == nil {
+			t.Errorf("%v with %s: got no error", c.args, c.impl)
+			continue
+		}
+		if got := errors.Is(err, errUsage); got != c.usage {
+			t.Errorf("%v with %s: got error %v, usage error: %v want: %v", c.args, c.impl, err, got, c.usage)
+		}
+	}
+}
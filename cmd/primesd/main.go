@@ -0,0 +1,59 @@
+// Command primesd serves queries about prime numbers over HTTP, as JSON.
+//
+// Endpoints, all GET:
+//
+//	/isprime?n=N        {"n": N, "prime": true|false}
+//	/count?n=N          {"n": N, "count": the number of primes up to N}
+//	/nth?k=K            {"k": K, "prime": the Kth prime, counting 2 as the first}
+//	/next?n=N           {"n": N, "prime": the smallest prime greater than N}
+//	/prev?n=N           {"n": N, "prime": the largest prime less than N}
+//	/factor?n=N         {"n": N, "factors": [N's prime factors, with multiplicity]}
+//	/range?lo=LO&hi=HI  The primes from LO through HI, as newline-delimited JSON.
+//
+// Errors are {"error": "..."}, with a 4xx or 5xx status. Past --max_inflight requests at once,
+// the rest get a 503 straight away.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"runtime"
+	"time"
+)
+
+var (
+	addr        = flag.String("addr", "localhost:8080", "The address to listen on.")
+	maxN        = flag.Int("max_n", 100000000, "The largest n to sieve up to, for /range, /count and /nth; /isprime sieves (and memoizes) up to n within it, and uses Miller-Rabin beyond it. (/next, /prev and /factor don't sieve.)")
+	maxRange    = flag.Int("max_range", 1000000, "The most numbers a /range may span.")
+	timeout     = flag.Duration("timeout", 10*time.Second, "How long to spend on a request before giving up on it.")
+	maxInFlight = flag.Int("max_inflight", runtime.GOMAXPROCS(0), "How many requests may be working at once, counting ones that timed out but are still sieving; more get a 503.")
+	help        = flag.Bool("help", false, "Display a usage message.")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "%s: Serve queries about prime numbers over HTTP.\nUsage:\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *help {
+		flag.Usage()
+		os.Exit(-1)
+	}
+	if *maxN < 3 || *maxRange < 1 || *timeout <= 0 || *maxInFlight < 1 {
+		fmt.Fprintln(os.Stderr, "--max_n must be at least 3, --max_range and --max_inflight at least 1, and --timeout positive")
+		os.Exit(-1)
+	}
+
+	s := newServer(limits{maxN: *maxN, maxRange: *maxRange, timeout: *timeout, maxInFlight: *maxInFlight})
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("serving on %s", *addr)
+	log.Fatal(srv.ListenAndServe())
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cceckman/primes"
+)
+
+// limits bounds the work that a single request can ask for.
+type limits struct {
+	// maxN is the largest n to sieve up to, for range, count and nth.
+	maxN int
+	// maxRange is the most numbers a range may span.
+	maxRange int
+	// timeout is how long to spend on a request before giving up on it.
+	timeout time.Duration
+	// maxInFlight is how many requests may be working at once, including ones that have timed
+	// out but whose work hasn't finished.
+	maxInFlight int
+}
+
+// server answers queries about primes, over HTTP, from a shared MemoizingPrimer.
+type server struct {
+	p      *primes.MemoizingPrimer
+	limits limits
+	mux    *http.ServeMux
+	// work holds a token for each request that's working, up to limits.maxInFlight.
+	work chan struct{}
+}
+
+func newServer(l limits) *server {
+	s := &server{
+		// Memoize no further than we'll sieve; IsPrime goes to Miller-Rabin beyond that.
+		p:      primes.NewMemoizingPrimer(primes.WithMaxMemoized(l.maxN)),
+		limits: l,
+		mux:    http.NewServeMux(),
+		work:   make(chan struct{}, l.maxInFlight),
+	}
+	s.mux.HandleFunc("/isprime", s.handle(s.isPrime))
+	s.mux.HandleFunc("/count", s.handle(s.count))
+	s.mux.HandleFunc("/nth", s.handle(s.nth))
+	s.mux.HandleFunc("/next", s.handle(s.next))
+	s.mux.HandleFunc("/prev", s.handle(s.prev))
+	s.mux.HandleFunc("/factor", s.handle(s.factor))
+	s.mux.HandleFunc("/range", s.primeRange)
+	return s
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeError(w, &httpError{code: http.StatusMethodNotAllowed, msg: "only GET is supported"})
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// httpError is an error with the HTTP status to report it with.
+type httpError struct {
+	code int
+	msg  string
+}
+
+func (e *httpError) Error() string {
+	return e.msg
+}
+
+func badRequest(format string, args ...any) error {
+	return &httpError{code: http.StatusBadRequest, msg: fmt.Sprintf(format, args...)}
+}
+
+var (
+	errTimeout = &httpError{code: http.StatusServiceUnavailable, msg: "request timed out"}
+	errBusy    = &httpError{code: http.StatusServiceUnavailable, msg: "too many requests in progress; try again later"}
+)
+
+// acquire takes a slot for a request to work in, if there's one free, and returns the function
+// that frees it.
+func (s *server) acquire() (release func(), ok bool) {
+	select {
+	case s.work <- struct{}{}:
+		return func() { <-s.work }, true
+	default:
+		return nil, false
+	}
+}
+
+// writeError writes err as a JSON object, with the status for it.
+func writeError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	var he *httpError
+	if errors.As(err, &he) {
+		code = he.code
+	} else if errors.Is(err, primes.ErrTooLarge) || errors.Is(err, primes.ErrOutOfMemoryBudget) {
+		code = http.StatusBadRequest
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// handle returns a handler that answers with whatever f returns, as JSON. f runs with the
+// request's timeout; if it takes longer, the client gets an error, and f finishes unobserved.
+// The Primers can't be interrupted, which is why there are limits on n; and f holds its slot
+// until it finishes, so clients that give up and retry can't pile up work without bound.
+func (s *server) handle(f func(r *http.Request) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		release, ok := s.acquire()
+		if !ok {
+			w.Header().Set("Retry-After", "1")
+			writeError(w, errBusy)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), s.limits.timeout)
+		defer cancel()
+
+		type result struct {
+			v   any
+			err error
+		}
+		done := make(chan result, 1)
+		go func() {
+			defer release()
+			v, err := f(r)
+			done <- result{v, err}
+		}()
+
+		select {
+		case <-ctx.Done():
+			writeError(w, errTimeout)
+		case res := <-done:
+			if res.err != nil {
+				writeError(w, res.err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(res.v)
+		}
+	}
+}
+
+// intParam returns the integer query parameter name.
+func intParam(r *http.Request, name string) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, badRequest("missing parameter %q", name)
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, badRequest("bad parameter %q: %v", name, err)
+	}
+	return n, nil
+}
+
+// sievable returns an error if n is beyond what we'll sieve up to.
+func (s *server) sievable(name string, n int) error {
+	if n > s.limits.maxN {
+		return badRequest("%s is %d, more than the limit of %d", name, n, s.limits.maxN)
+	}
+	return nil
+}
+
+func (s *server) isPrime(r *http.Request) (any, error) {
+	n, err := intParam(r, "n")
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		N     int  `json:"n"`
+		Prime bool `json:"prime"`
+	}{n, s.p.IsPrime(n)}, nil
+}
+
+func (s *server) count(r *http.Request) (any, error) {
+	n, err := intParam(r, "n")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sievable("n", n); err != nil {
+		return nil, err
+	}
+	count, err := primes.Count(n, s.p)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		N     int `json:"n"`
+		Count int `json:"count"`
+	}{n, count}, nil
+}
+
+func (s *server) nth(r *http.Request) (any, error) {
+	k, err := intParam(r, "k")
+	if err != nil {
+		return nil, err
+	}
+	if k < 1 {
+		return nil, badRequest("k is %d; the first prime is k=1", k)
+	}
+	// The kth prime is at most maxN if k <= maxN / log(maxN); see primes.Nth.
+	if maxK := int(float64(s.limits.maxN) / math.Log(float64(s.limits.maxN))); k > maxK {
+		return nil, badRequest("k is %d, more than the limit of %d", k, maxK)
+	}
+	prime, err := primes.Nth(k, s.p)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		K     int `json:"k"`
+		Prime int `json:"prime"`
+	}{k, prime}, nil
+}
+
+// neighbor is the answer to next and prev.
+type neighbor struct {
+	N     int `json:"n"`
+	Prime int `json:"prime"`
+}
+
+func (s *server) next(r *http.Request) (any, error) {
+	n, err := intParam(r, "n")
+	if err != nil {
+		return nil, err
+	}
+	prime, ok := primes.NextPrime(n)
+	if !ok {
+		return nil, badRequest("there's no prime after %d that fits in 64 bits", n)
+	}
+	return neighbor{n, prime}, nil
+}
+
+func (s *server) prev(r *http.Request) (any, error) {
+	n, err := intParam(r, "n")
+	if err != nil {
+		return nil, err
+	}
+	prime, ok := primes.PrevPrime(n)
+	if !ok {
+		return nil, badRequest("there's no prime before %d", n)
+	}
+	return neighbor{n, prime}, nil
+}
+
+func (s *server) factor(r *http.Request) (any, error) {
+	n, err := intParam(r, "n")
+	if err != nil {
+		return nil, err
+	}
+	if n < 1 {
+		return nil, badRequest("can't factor %d", n)
+	}
+	factors := primes.Factor(n)
+	if factors == nil {
+		factors = []int{}
+	}
+	return struct {
+		N       int   `json:"n"`
+		Factors []int `json:"factors"`
+	}{n, factors}, nil
+}
+
+// flushEvery is how many primes primeRange writes between flushes.
+const flushEvery = 1024
+
+// primeRange streams the primes from lo through hi, as newline-delimited JSON: a number per line.
+// If it times out partway, the last line is an error object instead.
+func (s *server) primeRange(w http.ResponseWriter, r *http.Request) {
+	lo, hi, err := s.rangeParams(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	s.streamRange(w, r, lo, hi)
+}
+
+// rangeParams returns the range that r asks for, if it's within limits.
+func (s *server) rangeParams(r *http.Request) (lo, hi int, err error) {
+	if lo, err = intParam(r, "lo"); err != nil {
+		return 0, 0, err
+	}
+	if hi, err = intParam(r, "hi"); err != nil {
+		return 0, 0, err
+	}
+	if hi < lo {
+		return 0, 0, badRequest("hi (%d) is less than lo (%d)", hi, lo)
+	}
+	if err := s.sievable("hi", hi); err != nil {
+		return 0, 0, err
+	}
+	// (If lo is very negative, the width overflows.)
+	if width := hi - lo + 1; width > s.limits.maxRange || width <= 0 {
+		return 0, 0, badRequest("the range from %d through %d is wider than the limit of %d", lo, hi, s.limits.maxRange)
+	}
+	return lo, hi, nil
+}
+
+// streamRange writes the primes in [lo, hi]. It sieves just that range, as it goes, so it stops
+// working as soon as it stops writing.
+func (s *server) streamRange(w http.ResponseWriter, r *http.Request, lo, hi int) {
+	release, ok := s.acquire()
+	if !ok {
+		w.Header().Set("Retry-After", "1")
+		writeError(w, errBusy)
+		return
+	}
+	defer release()
+	ctx, cancel := context.WithTimeout(r.Context(), s.limits.timeout)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	written := 0
+	for prime := range primes.PrimesBetween(lo, hi) {
+		if ctx.Err() != nil {
+			enc.Encode(map[string]string{"error": errTimeout.Error()})
+			return
+		}
+		if err := enc.Encode(prime); err != nil {
+			// The client's gone.
+			return
+		}
+		if written++; written%flushEvery == 0 && flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
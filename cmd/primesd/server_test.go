@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testServer(t *testing.T, l limits) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(newServer(l))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+var testLimits = limits{maxN: 100000, maxRange: 1000, timeout: 10 * time.Second, maxInFlight: 4}
+
+func get(t *testing.T, ts *httptest.Server, path string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(ts.URL + path)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestEndpoints(t *testing.T) {
+	ts := testServer(t, testLimits)
+	for _, c := range []struct {
+		path string
+		want string
+	}{
+		{"/isprime?n=97", `{"n":97,"prime":true}`},
+		{"/isprime?n=-1", `{"n":-1,"prime":false}`},
+		// Beyond maxN, but Miller-Rabin handles it.
+		{"/isprime?n=2305843009213693951", `{"n":2305843009213693951,"prime":true}`},
+		{"/count?n=1000", `{"n":1000,"count":168}`},
+		{"/nth?k=168", `{"k":168,"prime":997}`},
+		{"/next?n=997", `{"n":997,"prime":1009}`},
+		{"/prev?n=997", `{"n":997,"prime":991}`},
+		{"/factor?n=600851475143", `{"n":600851475143,"factors":[71,839,1471,6857]}`},
+		{"/factor?n=1", `{"n":1,"factors":[]}`},
+		{"/range?lo=90&hi=110", "97\n101\n103\n107\n109"},
+	} {
+		code, body := get(t, ts, c.path)
+		if code != http.StatusOK {
+			t.Errorf("GET %s: got status %d (%s) want: %d", c.path, code, body, http.StatusOK)
+			continue
+		}
+		if got := strings.TrimSpace(body); got != c.want {
+			t.Errorf("GET %s: got: %s want: %s", c.path, got, c.want)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	ts := testServer(t, testLimits)
+	for _, path := range []string{
+		"/isprime", "/isprime?n=x",
+		"/count?n=100001",
+		"/nth?k=0", "/nth?k=100000",
+		"/next?n=9223372036854775783", "/prev?n=2",
+		"/factor?n=0",
+		"/range?lo=1", "/range?lo=10&hi=1", "/range?lo=1&hi=2000", "/range?lo=99999&hi=100001",
+		"/range?lo=-9223372036854775808&hi=5",
+	} {
+		code, body := get(t, ts, path)
+		if code != http.StatusBadRequest {
+			t.Errorf("GET %s: got status %d (%s) want: %d", path, code, body, http.StatusBadRequest)
+		}
+		var e struct{ Error string }
+		if err := json.Unmarshal([]byte(body), &e); err != nil || e.Error == "" {
+			t.Errorf("GET %s: got body %q, want an error object", path, body)
+		}
+	}
+	if code, _ := get(t, ts, "/nope"); code != http.StatusNotFound {
+		t.Errorf("GET /nope: got status %d want: %d", code, http.StatusNotFound)
+	}
+	resp, err := http.Post(ts.URL+"/isprime?n=7", "text/plain", nil)
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST /isprime: got status %d want: %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	l := testLimits
+	l.maxN, l.maxRange, l.timeout = 10000000, 10000000, time.Nanosecond
+	ts := testServer(t, l)
+	if code, body := get(t, ts, "/count?n=10000000"); code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d (%s) want: %d", code, body, http.StatusServiceUnavailable)
+	}
+	_, body := get(t, ts, "/range?lo=1&hi=10000000")
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	if last := lines[len(lines)-1]; !strings.Contains(last, "error") {
+		t.Errorf("range: got last line %q, want an error", last)
+	}
+}
+
+func TestLargeRange(t *testing.T) {
+	ts := testServer(t, limits{maxN: 1000000, maxRange: 1000000, timeout: 10 * time.Second, maxInFlight: 1})
+	resp, err := http.Get(ts.URL + "/range?lo=1&hi=1000000")
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("got Content-Type %q", ct)
+	}
+	s := bufio.NewScanner(resp.Body)
+	count, last := 0, 0
+	for s.Scan() {
+		n, err := strconv.Atoi(s.Text())
+		if err != nil {
+			t.Fatalf("line %d: %v", count+1, err)
+		}
+		if n <= last {
+			t.Fatalf("line %d: %d isn't after %d", count+1, n, last)
+		}
+		count, last = count+1, n
+	}
+	if count != 78498 {
+		t.Errorf("got %d primes want: %d", count, 78498)
+	}
+}
+
+func TestInFlight(t *testing.T) {
+	l := testLimits
+	l.timeout = 10 * time.Millisecond
+	s := newServer(l)
+	// A slow query, that keeps working after it times out, until it's unblocked.
+	unblock := make(chan struct{})
+	s.mux.HandleFunc("/slow", s.handle(func(*http.Request) (any, error) {
+		<-unblock
+		return "done", nil
+	}))
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+
+	const requests = 10
+	bodies := make(chan string, requests)
+	for i := 0; i < requests; i++ {
+		go func() {
+			code, body := get(t, ts, "/slow")
+			if code != http.StatusServiceUnavailable {
+				t.Errorf("got status %d (%s) want: %d", code, body, http.StatusServiceUnavailable)
+			}
+			bodies <- body
+		}()
+	}
+	timedOut, busy := 0, 0
+	for i := 0; i < requests; i++ {
+		switch body := <-bodies; {
+		case strings.Contains(body, errTimeout.msg):
+			timedOut++
+		case strings.Contains(body, errBusy.msg):
+			busy++
+		}
+	}
+	if timedOut != l.maxInFlight || busy != requests-l.maxInFlight {
+		t.Errorf("got %d timed out and %d turned away, want: %d and %d", timedOut, busy, l.maxInFlight, requests-l.maxInFlight)
+	}
+	// Every slot is still taken by work that's timed out: even a quick query is turned away.
+	if code, body := get(t, ts, "/isprime?n=7"); !strings.Contains(body, errBusy.msg) {
+		t.Errorf("while busy: got status %d (%s), want: %s", code, body, errBusy.msg)
+	}
+
+	// Once the work finishes, its slots free up.
+	close(unblock)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		code, body := get(t, ts, "/isprime?n=7")
+		if code == http.StatusOK {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("after unblocking: got status %d (%s)", code, body)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
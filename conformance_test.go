@@ -0,0 +1,19 @@
+package primes_test
+
+import (
+	"testing"
+
+	"github.com/cceckman/primes"
+	"github.com/cceckman/primes/primestest"
+)
+
+// TestConformance runs the conformance suite on every registered Primer.
+// It's in package primes_test, since primestest imports primes.
+func TestConformance(t *testing.T) {
+	for _, name := range primes.Names() {
+		factory, _, _ := primes.Lookup(name)
+		t.Run(name, func(t *testing.T) {
+			primestest.Run(t, factory)
+		})
+	}
+}
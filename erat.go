@@ -12,6 +12,7 @@ func (p *simpleErat) PrimesUpTo(n int, out chan<- int) {
 	// No primes less than or equal to 1.
 	if n <= 1 {
 		close(out)
+		return
 	}
 
 	composite := make([]bool, n+1)
@@ -290,7 +291,11 @@ func (p *erat4) IsPrime(n int) bool {
 
 // erat5 is like erat4, but also:
 // Starts the sieve (multiples of i) at i^2.
-type erat5 struct{}
+type erat5 struct {
+	// presieved initializes the sieve from a precomputed pattern of the multiples of small
+	// primes, rather than crossing them off one at a time. (This is "Erat6".)
+	presieved bool
+}
 
 func (p *erat5) PrimesUpTo(n int, out chan<- int) {
 	if n <= 1 {
@@ -309,28 +314,39 @@ func (p *erat5) PrimesUpTo(n int, out chan<- int) {
 	// number n is at index (n-1) / 2
 	// prime = not-composite, until proven otherwise.
 	composite := make([]bool, n / 2 + 1)
+	if p.presieved {
+		presieve(composite, 0)
+	}
 	composite[0] = true  // 1 is, well, not prime.
 
 	// Only need to look for primes "less than or equal to" sqrt(n)
 	// before assuming all remaining (un-sieved) ones are prime
 	sqrt := int(math.Ceil(math.Sqrt(float64(n))))
-	for i := 1; i <= n; i += 2 {
-		if composite[(i - 1) / 2] { // non-default; has been explicitly set to be composite.
+	// Walk indices rather than numbers: near MaxN, i += 2 past n could overflow.
+	for k := range composite {
+		if composite[k] { // non-default; has been explicitly set to be composite.
 			continue
 		}
+		i := k*2 + 1
+		if i > n {
+			// composite has a spare slot when n is even.
+			break
+		}
 		// Found a prime; record it...
 		out <- i
 
-		if i > sqrt {
-			// Skip sieving; we've covered all the primes already.
+		if i > sqrt || (p.presieved && i <= presieveMax) {
+			// Skip sieving; either we've covered all the primes already, or the pattern has
+			// covered this one.
 			continue
 		}
 
 		// run through odd multiples of i, marking as composite.
 		// Start with i * i; lower multiples of i will have already been marked as multiples
-		// of another, smaller prime. Add 2i each time to ignore the even multiples.
-		for j := i * i; j <= n; j += (i + i) {
-			composite[(j - 1) / 2] = true
+		// of another, smaller prime. Odd multiples are 2i apart, i.e. i indices apart;
+		// stepping by index can't overflow.
+		for j := (i*i - 1) / 2; j < len(composite); j += i {
+			composite[j] = true
 		} // end sieve
 	}
 	close(out)
@@ -360,5 +376,3 @@ func (p *erat5) IsPrime(n int) bool {
 	}
 	return false
 }
-
-
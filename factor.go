@@ -0,0 +1,91 @@
+package primes
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// trialDivisors is how far Factor divides by trial before switching to Pollard's rho.
+const trialDivisors = 1 << 10
+
+// Factor returns the prime factors of n, in ascending order and with multiplicity, so that their
+// product is n. It returns nil for n < 2.
+// Small factors are found by trial division; large ones by Pollard's rho, checked with Miller-Rabin.
+func Factor(n int) []int {
+	if n < 2 {
+		return nil
+	}
+	var factors []int
+	for n%2 == 0 {
+		factors = append(factors, 2)
+		n /= 2
+	}
+	for d := 3; d < trialDivisors && d <= n/d; d += 2 {
+		for n%d == 0 {
+			factors = append(factors, d)
+			n /= d
+		}
+	}
+	if n == 1 {
+		return factors
+	}
+
+	// Whatever's left has only large factors.
+	pending := []uint64{uint64(n)}
+	for len(pending) > 0 {
+		m := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if millerRabin(int(m)) {
+			factors = append(factors, int(m))
+			continue
+		}
+		d := rho(m)
+		pending = append(pending, d, m/d)
+	}
+	sort.Ints(factors)
+	return factors
+}
+
+// rho returns a non-trivial factor of n, which must be an odd composite, by Pollard's rho.
+func rho(n uint64) uint64 {
+	for c := uint64(1); ; c++ {
+		// Iterate x -> x^2 + c mod n; n < 2^63, so the sum can't overflow.
+		f := func(x uint64) uint64 { return (mulMod(x, x, n) + c) % n }
+		x, y, d := uint64(2), uint64(2), uint64(1)
+		for d == 1 {
+			x, y = f(x), f(f(y))
+			d = gcd(diff(x, y), n)
+		}
+		if d != n {
+			return d
+		}
+		// The cycle closed without finding a factor; try another polynomial.
+	}
+}
+
+func diff(a, b uint64) uint64 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// gcd returns the greatest common divisor of a and b, by the binary GCD algorithm.
+func gcd(a, b uint64) uint64 {
+	if a == 0 {
+		return b
+	}
+	if b == 0 {
+		return a
+	}
+	shift := bits.TrailingZeros64(a | b)
+	a >>= uint(bits.TrailingZeros64(a))
+	for b != 0 {
+		b >>= uint(bits.TrailingZeros64(b))
+		if a > b {
+			a, b = b, a
+		}
+		b -= a
+	}
+	return a << uint(shift)
+}
@@ -0,0 +1,132 @@
+package primes
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+)
+
+// An op is one call in a fuzzed sequence: IsPrime(n), or PrimesUpTo(n).
+type op struct {
+	upTo bool
+	n    int
+}
+
+const (
+	// opBytes is how many bytes of fuzz input encode an op: a byte whose low bit picks the call,
+	// then n, as a little-endian uint16 offset by opOffset (so that n may be negative).
+	opBytes  = 3
+	opOffset = 8
+	// maxOps is the most ops in a sequence; the rest of the input is ignored.
+	maxOps = 16
+	// fuzzMax is the largest n an op may have.
+	fuzzMax = 1<<16 - 1 - opOffset
+)
+
+// decodeOps decodes a sequence of ops from fuzz input.
+func decodeOps(data []byte) []op {
+	var ops []op
+	for ; len(data) >= opBytes && len(ops) < maxOps; data = data[opBytes:] {
+		ops = append(ops, op{
+			upTo: data[0]&1 == 1,
+			n:    int(data[1]) | int(data[2])<<8 - opOffset,
+		})
+	}
+	return ops
+}
+
+// encodeOps is the inverse of decodeOps, for seeding the corpus.
+func encodeOps(ops ...op) []byte {
+	var data []byte
+	for _, o := range ops {
+		var b byte
+		if o.upTo {
+			b = 1
+		}
+		n := o.n + opOffset
+		data = append(data, b, byte(n), byte(n>>8))
+	}
+	return data
+}
+
+var (
+	oracleOnce  sync.Once
+	oracleTable []bool
+)
+
+// oracle returns whether n is prime, by math/big's test (which is exact below 2^64): it shares
+// no code with the Primers it checks. The answers up to fuzzMax are worked out once.
+func oracle(n int) bool {
+	oracleOnce.Do(func() {
+		oracleTable = make([]bool, fuzzMax+1)
+		for i := range oracleTable {
+			oracleTable[i] = big.NewInt(int64(i)).ProbablyPrime(0)
+		}
+	})
+	return n >= 0 && oracleTable[n]
+}
+
+// checkUpTo checks that got is the primes up to n, in order.
+func checkUpTo(t *testing.T, name string, n int, got []int) {
+	t.Helper()
+	k := 0
+	for i := 2; i <= n; i++ {
+		if !oracle(i) {
+			continue
+		}
+		if k >= len(got) || got[k] != i {
+			t.Errorf("%s: PrimesUpTo(%d): prime #%d is %d, but got: %v", name, n, k+1, i, got[k:min(k+3, len(got))])
+			return
+		}
+		k++
+	}
+	if k != len(got) {
+		t.Errorf("%s: PrimesUpTo(%d): got %d primes, then extra: %v", name, n, k, got[k:min(k+3, len(got))])
+	}
+}
+
+// FuzzOps runs a sequence of IsPrime and PrimesUpTo calls against a fresh instance of each
+// registered Primer, and against a DB, checking every answer against the oracle. It's meant to
+// find bugs, like the ones in TestRegression, that only show up after certain earlier calls.
+func FuzzOps(f *testing.F) {
+	// The sequences of TestRegression.
+	for _, first := range []op{{false, 1683}, {false, 11}, {false, 12}, {true, 11}, {true, 12}} {
+		f.Add(encodeOps(first, op{false, 1765}))
+	}
+	f.Add(encodeOps(op{false, 1765}))
+	f.Add(encodeOps(op{true, -1}, op{false, 0}, op{false, 1}, op{true, 2}, op{false, 2}))
+	f.Add(encodeOps(op{true, 1000}, op{true, 100}, op{false, 997}, op{true, fuzzMax}, op{false, 65521}))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ops := decodeOps(data)
+		for name, p := range registered(all) {
+			for _, o := range ops {
+				if o.upTo {
+					checkUpTo(t, name, o.n, PrimesUpTo(o.n, p))
+				} else if got, want := p.IsPrime(o.n), oracle(o.n); got != want {
+					t.Errorf("%s: IsPrime(%d): got: %v want: %v", name, o.n, got, want)
+				}
+			}
+		}
+
+		// DB has no PrimesUpTo; an Iterator stands in for it.
+		db := New()
+		for _, o := range ops {
+			if !o.upTo {
+				if got, want := db.IsPrime(o.n), oracle(o.n); got != want {
+					t.Errorf("DB: IsPrime(%d): got: %v want: %v", o.n, got, want)
+				}
+				continue
+			}
+			var got []int
+			for it := db.Iterator(); ; {
+				p := it.Next()
+				if p > o.n {
+					break
+				}
+				got = append(got, p)
+			}
+			checkUpTo(t, "DB", o.n, got)
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package primes
+
+import (
+	"iter"
+)
+
+// Gap is the gap between consecutive primes: Start is prime, Start+Length is the next prime,
+// and there are none in between.
+type Gap struct {
+	Start, Length int
+}
+
+// Gaps returns the gaps between consecutive primes in [lo, hi], in order: both ends of each gap
+// are in the range. hi is capped at MaxN.
+// Like PrimesBetween, it never holds the list of primes; its memory grows only with sqrt(hi).
+func Gaps(lo, hi int) iter.Seq[Gap] {
+	return func(yield func(Gap) bool) {
+		prev := 0
+		primesBetween(lo, hi, func(p int) bool {
+			if prev > 0 && !yield(Gap{Start: prev, Length: p - prev}) {
+				return false
+			}
+			prev = p
+			return true
+		})
+	}
+}
+
+// MaximalGaps returns the maximal gaps among the primes up to upTo, in order: those longer than
+// every gap before them.
+func MaximalGaps(upTo int) []Gap {
+	var records []Gap
+	for g := range Gaps(2, upTo) {
+		if len(records) == 0 || g.Length > records[len(records)-1].Length {
+			records = append(records, g)
+		}
+	}
+	return records
+}
+
+// GapHistogram returns how many of the gaps in [lo, hi], as Gaps returns them, have each length.
+func GapHistogram(lo, hi int) map[int]int {
+	counts := make(map[int]int)
+	for g := range Gaps(lo, hi) {
+		counts[g.Length]++
+	}
+	return counts
+}
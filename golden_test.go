@@ -0,0 +1,169 @@
+package primes
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var goldenMax = flag.Int("golden_max", 8, "How far to check Primers against testdata: up to 10^k for this k (at most 6 with -short, and 10 in all).")
+
+// golden is a line of testdata/pi.txt: a summary of the primes up to x.
+type golden struct {
+	x, count int
+	sum, xor uint64
+}
+
+// readTestdata returns the whitespace-separated fields of each line of the named file in
+// testdata, skipping blank lines and #-comments.
+func readTestdata(t *testing.T, name string) [][]string {
+	t.Helper()
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var lines [][]string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if line := strings.TrimSpace(s.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, strings.Fields(line))
+		}
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return lines
+}
+
+// parseUints parses fields into dst, in order.
+func parseUints(fields []string, dst ...*uint64) error {
+	if len(fields) != len(dst) {
+		return fmt.Errorf("got %d fields, want %d", len(fields), len(dst))
+	}
+	for i, f := range fields {
+		v, err := strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			return err
+		}
+		*dst[i] = v
+	}
+	return nil
+}
+
+// readGoldens reads testdata/pi.txt, and the Gaps in testdata/maximal_gaps.txt.
+func readGoldens(t *testing.T) ([]golden, []Gap) {
+	var goldens []golden
+	for _, fields := range readTestdata(t, "pi.txt") {
+		var x, count, sum, xor uint64
+		if err := parseUints(fields, &x, &count, &sum, &xor); err != nil {
+			t.Fatalf("pi.txt: %v", err)
+		}
+		goldens = append(goldens, golden{int(x), int(count), sum, xor})
+	}
+	var gaps []Gap
+	for _, fields := range readTestdata(t, "maximal_gaps.txt") {
+		var length, start uint64
+		if err := parseUints(fields, &length, &start); err != nil {
+			t.Fatalf("maximal_gaps.txt: %v", err)
+		}
+		gaps = append(gaps, Gap{Start: int(start), Length: int(length)})
+	}
+	return goldens, gaps
+}
+
+// TestGolden checks each registered Primer's PrimesUpTo against published values: pi(10^k),
+// checksums of the primes up to 10^k, and the maximal gaps between them. It checks the tiers
+// up to 10^--golden_max (10^6 with -short) in one pass, so far as each Primer's limits allow.
+func TestGolden(t *testing.T) {
+	goldens, gaps := readGoldens(t)
+	top := *goldenMax
+	if testing.Short() {
+		top = min(top, 6)
+	}
+	if top > len(goldens) {
+		t.Fatalf("--golden_max=%d: testdata only goes up to 10^%d", top, len(goldens))
+	}
+
+	for name, p := range registered(all) {
+		tiers := goldens[:top]
+		for len(tiers) > 0 && Check(tiers[len(tiers)-1].x, p) != nil {
+			tiers = tiers[:len(tiers)-1]
+		}
+		if len(tiers) == 0 {
+			t.Logf("%s: skipping; it can't go up to %d", name, goldens[0].x)
+			continue
+		}
+		if len(tiers) < top {
+			t.Logf("%s: only checking up to %d", name, tiers[len(tiers)-1].x)
+		}
+		n := tiers[len(tiers)-1].x
+
+		if _, info, _ := Lookup(name); info.Stateful {
+			// Have the pass pick up from what's memoized, at odd and even bounds, rather than
+			// start from scratch.
+			p.IsPrime(1765)
+			PrimesUpTo(12346, p)
+			p.IsPrime(99991)
+		}
+
+		var got golden
+		var records []Gap
+		prev := 0
+		c := make(chan int, 1024)
+		go p.PrimesUpTo(n, c)
+		for v := range c {
+			for len(tiers) > 0 && v > tiers[0].x {
+				got.x = tiers[0].x
+				if got != tiers[0] {
+					t.Errorf("%s: up to %d: got: %+v want: %+v", name, tiers[0].x, got, tiers[0])
+				}
+				tiers = tiers[1:]
+			}
+			if prev > 0 && (len(records) == 0 || v-prev > records[len(records)-1].Length) {
+				records = append(records, Gap{Start: prev, Length: v - prev})
+			}
+			prev = v
+			got.count++
+			got.sum += uint64(v)
+			got.xor ^= uint64(v)
+		}
+		for _, want := range tiers {
+			got.x = want.x
+			if got != want {
+				t.Errorf("%s: up to %d: got: %+v want: %+v", name, want.x, got, want)
+			}
+		}
+
+		// The maximal gaps that end by n.
+		want := gaps
+		for len(want) > 0 && want[len(want)-1].Start+want[len(want)-1].Length > n {
+			want = want[:len(want)-1]
+		}
+		if fmt.Sprint(records) != fmt.Sprint(want) {
+			t.Errorf("%s: maximal gaps up to %d: got: %v want: %v", name, n, records, want)
+		}
+	}
+}
+
+// TestMaximalGaps checks MaximalGaps against testdata, up to 10^--golden_max (10^6 with -short).
+func TestMaximalGaps(t *testing.T) {
+	goldens, gaps := readGoldens(t)
+	k := min(*goldenMax, len(goldens))
+	if testing.Short() {
+		k = min(k, 6)
+	}
+	n := goldens[k-1].x
+	want := gaps
+	for len(want) > 0 && want[len(want)-1].Start+want[len(want)-1].Length > n {
+		want = want[:len(want)-1]
+	}
+	if got := MaximalGaps(n); !reflect.DeepEqual(got, want) {
+		t.Errorf("up to %d: got: %v want: %v", n, got, want)
+	}
+}
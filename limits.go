@@ -0,0 +1,93 @@
+package primes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// MaxN is the largest n that the sieves accept.
+// Leaving headroom of a few multiples of sqrt(math.MaxInt) guarantees that stepping past n,
+// e.g. by twice a sieving prime, can't overflow.
+const MaxN = math.MaxInt - 4*(1<<(bits.UintSize/2))
+
+// DefaultMaxMemory is the memory budget, in bytes, used by PrimesUpToE for Primers that don't
+// have a budget of their own.
+const DefaultMaxMemory = 1 << 30
+
+var (
+	// ErrTooLarge indicates a request beyond MaxN.
+	ErrTooLarge = errors.New("primes: n too large")
+	// ErrOutOfMemoryBudget indicates a request that would need more memory than allowed.
+	ErrOutOfMemoryBudget = errors.New("primes: out of memory budget")
+)
+
+// checker is implemented by Primers that know up front whether they can handle n.
+type checker interface {
+	// check returns nil if PrimesUpTo(n, ...) can proceed, or an error explaining why not.
+	check(n int) error
+}
+
+// budgeter is implemented by Primers with a memory budget of their own, e.g. from WithMaxMemory.
+type budgeter interface {
+	// budget returns the most memory, in bytes, that the Primer may use.
+	budget() int
+}
+
+// memoryBudget returns p's memory budget, or DefaultMaxMemory if it doesn't have one.
+func memoryBudget(p Primer) int {
+	if b, ok := p.(budgeter); ok {
+		return b.budget()
+	}
+	return DefaultMaxMemory
+}
+
+// Check returns nil if p can find the primes up to n, or ErrTooLarge or ErrOutOfMemoryBudget
+// if not. Primers without limits of their own are held to DefaultMaxMemory.
+// (IsPrime(n) costs most Primers as much as PrimesUpTo(n), so Check is a fair test for it too.)
+func Check(n int, p Primer) error {
+	if c, ok := p.(checker); ok {
+		return c.check(n)
+	}
+	return checkOdds(n, DefaultMaxMemory)
+}
+
+// piUpperBound returns an upper bound on pi(n), the number of primes less than or equal to n.
+// (Rosser & Schoenfeld: pi(x) < 1.25506 x / log x for x > 1.)
+func piUpperBound(n int) int {
+	if n < 2 {
+		return 0
+	}
+	if n < 17 {
+		// The bound is loose for small x, but not this loose; keep the logarithm away from 0.
+		return 7
+	}
+	return int(1.25506*float64(n)/math.Log(float64(n))) + 1
+}
+
+// checkSieve returns the error, if any, for sieving up to n using sieveBytes bytes
+// within a budget of maxMemory bytes.
+func checkSieve(n, sieveBytes, maxMemory int) error {
+	if n > MaxN {
+		return fmt.Errorf("%w: %d is greater than %d", ErrTooLarge, n, MaxN)
+	}
+	if sieveBytes > maxMemory {
+		return fmt.Errorf("%w: sieving up to %d needs %d bytes, more than %d", ErrOutOfMemoryBudget, n, sieveBytes, maxMemory)
+	}
+	return nil
+}
+
+// checkOdds is checkSieve for the usual odds-only sieve, which uses a bool per odd number.
+func checkOdds(n, maxMemory int) error {
+	return checkSieve(n, n/2+1, maxMemory)
+}
+
+func (p *MemoizingPrimer) budget() int { return p.opts.maxMemory }
+func (p *segErat) budget() int         { return p.opts.maxMemory }
+func (p *auto) budget() int            { return p.opts.maxMemory }
+
+func (p *simpleErat) check(n int) error {
+	// simpleErat doesn't skip even numbers, so it needs twice the memory of the others.
+	return checkSieve(n, n+1, DefaultMaxMemory)
+}
@@ -8,6 +8,7 @@
 package primes
 
 import (
+	"fmt"
 	"math"
 	"sync"
 	"sync/atomic"
@@ -18,23 +19,62 @@ var (
 )
 
 // MemoizingPrimer is primer that stores found primes.
-// It is threadsafe... probably.
+// It is safe for concurrent use, including Shrink and Reset alongside queries.
 type MemoizingPrimer struct {
-	// max is the largest number checked for primacy
+	// max is the largest number checked for primacy.
+	// It's only written with the write lock held, but may be read atomically without it.
 	max int64
 	// listing is the list of all primes found below max.
-	listed []int
+	listed primeList
 	lock   sync.RWMutex
+
+	opts options
 }
 
-func NewMemoizingPrimer() *MemoizingPrimer {
+// NewMemoizingPrimer returns an empty MemoizingPrimer.
+// WithMaxMemory bounds the memory it uses for its table and sieve, and WithMaxMemoized bounds the
+// range its table covers. Past either bound, it answers queries without memoizing them.
+func NewMemoizingPrimer(opts ...Option) *MemoizingPrimer {
 	p := &MemoizingPrimer{
-		max:    10,
-		listed: []int{2, 3, 5, 7},
+		opts: newOptions(opts),
 	}
+	p.reset()
 	return p
 }
 
+// reset returns p to its initial state. The caller must hold the write lock, or be the only user of p.
+func (p *MemoizingPrimer) reset() {
+	p.listed = newPrimeList(2, 3, 5, 7)
+	atomic.StoreInt64(&p.max, 10)
+}
+
+// Reset discards everything p has memoized, releasing its memory.
+func (p *MemoizingPrimer) Reset() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.reset()
+}
+
+// Shrink discards the primes p has memoized beyond max, releasing their memory.
+func (p *MemoizingPrimer) Shrink(max int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if max >= int(p.max) {
+		return
+	}
+	if max < 10 {
+		p.reset()
+		return
+	}
+	// Copy, rather than reslice, so that the old table can actually be freed.
+	listed := primeList{}
+	for i, end := 0, p.listed.search(max+1); i < end; i++ {
+		listed.append(p.listed.at(i))
+	}
+	p.listed = listed
+	atomic.StoreInt64(&p.max, int64(max))
+}
+
 // IsPrime returns whether or not n is prime. It blocks until it can determine a result.
 func (p *MemoizingPrimer) IsPrime(n int) bool {
 	// Quick answers.
@@ -50,56 +90,105 @@ func (p *MemoizingPrimer) IsPrime(n int) bool {
 
 	// Compare without taking locks, so as to not block.
 	if n > int(atomic.LoadInt64(&p.max)) {
-		p.computeUpTo(n)
+		if n > p.opts.maxMemoized || p.computeUpTo(n) != nil {
+			// We can't memoize that far; work it out without doing so.
+			return millerRabin(n)
+		}
 	}
 
-	// We have successfully asserted that listed includes at least up to n.
 	p.lock.RLock()
 	defer p.lock.RUnlock()
-
-	// Log rather than linear: binary search.
-	search := p.listed[:]
-	for len(search) > 0 {
-		mid := len(search) / 2
-		if search[mid] == n {
-			return true
-		}
-		if search[mid] > n { // search lower half
-			search = search[:mid]
-		} else if search[mid] < n { // search upper half
-			search = search[mid+1:]
-		}
+	if n > int(p.max) {
+		// Someone Shrank p in the meantime.
+		return millerRabin(n)
 	}
 
-	return false
+	// We have successfully asserted that listed includes at least up to n.
+	// Log rather than linear: binary search.
+	return p.listed.contains(n)
 }
 
 // PrimesUpTo streams all the primes up to n, and closes 'out' when complete.
 // It's non-blocking.
+// If the primes up to n don't fit in p's budget or range, p memoizes those it can and sieves
+// the rest segment by segment as it streams them. If even that doesn't fit, 'out' is closed
+// early; use PrimesUpToE to get an error instead.
 func (p *MemoizingPrimer) PrimesUpTo(n int, out chan<- int) {
-	p.computeUpTo(n)
-	// We have now asserted we're caught up.
+	if err := p.computeUpTo(min(n, p.opts.maxMemoized)); err != nil {
+		// We only need the primes up to sqrt(n) to sieve the rest.
+		p.computeUpTo(min(isqrt(n), p.opts.maxMemoized))
+	}
+	// We have now asserted we're caught up, or as caught up as we can be.
 
 	go func() {
 		p.lock.RLock()
-		curList := p.listed[:]
+		// listed is only ever appended to, so a copy of it stays valid after we unlock.
+		curList := p.listed
+		max := int(p.max)
 		p.lock.RUnlock()
 
-		for _, v := range curList {
-			if v <= n {
+		for i := 0; i < curList.len(); i++ {
+			if v := curList.at(i); v <= n {
 				out <- v
 			}
 		}
+		if n > max {
+			p.stream(&curList, max, n, out)
+		}
 		close(out)
 	}()
 }
 
+// stream sends the primes in (max, n] to out, sieving them segment by segment within p's budget,
+// without memoizing them. known must hold all the primes up to max.
+func (p *MemoizingPrimer) stream(known *primeList, max, n int, out chan<- int) {
+	avail := p.opts.maxMemory - known.bytes()
+	if sqrt := isqrt(n); max < sqrt {
+		// Our table doesn't reach sqrt(n); list the primes up to there separately.
+		base := basePrimes(sqrt)
+		known = &base
+		avail -= sqrt/2 + base.bytes()
+	}
+	if avail < minSegmentSize {
+		return
+	}
+	// Start with max+1 or max+2, whichever is odd.
+	lo := max + 1 + max%2
+	if lo > n {
+		return
+	}
+	composite := make([]bool, min(avail, defaultSegmentSize, (n-lo)/2+1))
+	sieveRange(composite, lo, n, known, func(i int) bool {
+		out <- i
+		return true
+	})
+}
+
+// check reports whether PrimesUpTo can produce all the primes up to n, within p's budget.
+func (p *MemoizingPrimer) check(n int) error {
+	if n > MaxN {
+		return fmt.Errorf("%w: %d is greater than %d", ErrTooLarge, n, MaxN)
+	}
+	// At worst, we stream the primes beyond our table, which needs the primes up to sqrt(n)
+	// (and a sieve to find them) and a segment to sieve in.
+	sqrt := isqrt(n)
+	if sqrt/2+piUpperBound(sqrt)*intSize+minSegmentSize > p.opts.maxMemory {
+		return fmt.Errorf("%w: sieving up to %d needs more than %d bytes", ErrOutOfMemoryBudget, n, p.opts.maxMemory)
+	}
+	return nil
+}
+
 // computeUpTo is a blocking call that returns once p has computed primes up to n.
-func (p *MemoizingPrimer) computeUpTo(n int) {
+// If the primes up to n won't fit in p's budget, it computes nothing and returns
+// ErrOutOfMemoryBudget.
+func (p *MemoizingPrimer) computeUpTo(n int) error {
 	// Do an initial, atomic check of the value. If n is smaller than max, we don't even need to
 	// bother taking the (expensive) write lock.
 	if n <= int(atomic.LoadInt64(&p.max)) {
-		return
+		return nil
+	}
+	if n > MaxN {
+		return fmt.Errorf("%w: %d is greater than %d", ErrTooLarge, n, MaxN)
 	}
 	// We may need to compute more more; take the write lock.
 	p.lock.Lock()
@@ -109,57 +198,39 @@ func (p *MemoizingPrimer) computeUpTo(n int) {
 	// before the other gets the lock. We do the initial, unlocked check so that we aren't taking
 	// the write lock (which blocks reads) in most cases; we double-check so that we don't overwrite
 	// existing results, or worse, go backwards in p.max.
-	if n <= int(p.max) {
-		return
+	max := int(p.max)
+	if n <= max {
+		return nil
 	}
 
-	// In an odds-only slice,
-	// index i refers to the number (i*2)+1;
-	// number n is at index (n-1) / 2
-	// (We could use less memory by subtracting out p.max.)
-	// prime = not-composite, until proven otherwise.
-	composite := make([]bool, (n/2)+1)
-	composite[0] = true // 1 is not prime
-
-	// First, mark composites from already-known primes.
-	// Skip the first prime (2), since it isn't even in our array.
-	for _, prime := range p.listed[1:] {
-		// We could optimize this by starting at the first *odd* multiple of prime greater than or
-		// equal to p.max. Instead, just start at prime*prime. (All smaller multiples of prime have
-		// another prime factor, that is smaller than prime.)
-		for i := prime * prime; i <= n; i += (prime * 2) {
-			composite[(i-1)/2] = true
-		}
+	// The table has to fit in the budget once it's grown to n, with room to spare for sieving.
+	// Growing it copies the old table into the new one, so count both.
+	elemSize := 4
+	if n > math.MaxUint32 {
+		elemSize = intSize
 	}
-
-	// Only need to look for primes "less than or equal to" sqrt(n)
-	// before assuming all remaining (un-sieved) ones are prime
-	sqrt := int(math.Ceil(math.Sqrt(float64(n))))
-	// Now, walk up, checking / marking composites along the way.
-	// Start with p.max or p.max-1, whichever is odd.
-	oddMax := int(p.max) - 1 + (int(p.max) % 2)
-	for i := oddMax; i <= n; i += 2 {
-		if composite[(i-1)/2] { // non-default; has been explicitly set to be composite.
-			continue
-		}
-		// Found a prime; record it.
-		p.listed = append(p.listed, int(i))
-
-		if i > sqrt {
-			// Skip sieving; we've covered all the primes already.
-			continue
-		}
-
-		// Found a new prime.
-		// run through odd multiples of i, marking as composite.
-		// Start with i * i; lower multiples of i will have already been marked as multiples
-		// of another, smaller prime. Add 2i each time to ignore the even multiples.
-		for j := i * i; j <= n; j += (i + i) {
-			composite[(j-1)/2] = true
-		} // end sieve
+	table := p.listed.bytes() + piUpperBound(n)*elemSize
+	avail := p.opts.maxMemory - table
+	if avail < minSegmentSize {
+		return fmt.Errorf("%w: memoizing up to %d needs more than %d bytes", ErrOutOfMemoryBudget, n, p.opts.maxMemory)
 	}
+
+	// Make room for the table up front: growing it by appending could take up to twice as much.
+	p.listed.reserve(n)
+
+	// Sieve (max, n], a segment at a time.
+	// Start with max+1 or max+2, whichever is odd.
+	lo := max + 1 + max%2
+	composite := make([]bool, min(avail, defaultSegmentSize, (n-lo)/2+1))
+	sieveRange(composite, lo, n, &p.listed, func(i int) bool {
+		p.listed.append(i)
+		// Record progress as we go, so lock-free readers can see it.
+		atomic.StoreInt64(&p.max, int64(i))
+		return true
+	})
 	// Finally, update max.
-	p.max = int64(n)
+	atomic.StoreInt64(&p.max, int64(n))
+	return nil
 }
 
 // backgroundFlush starts a thread that flushes c.
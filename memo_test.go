@@ -0,0 +1,251 @@
+package primes
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// A memoOp is one kind of call on a MemoizingPrimer, in a recorded history.
+type memoOp int
+
+const (
+	opGrow memoOp = iota
+	opShrink
+	opIsPrime
+	opPrimesUpTo
+	// opSnapshot reads max, and checks the table against it, under the read lock.
+	opSnapshot
+	numMemoOps
+)
+
+func (o memoOp) String() string {
+	return [...]string{"grow", "Shrink", "IsPrime", "PrimesUpTo", "snapshot"}[o]
+}
+
+// memoEvent is a call in a recorded history: what it was, what it returned (for opSnapshot;
+// the other calls' results are checked as they return), and when it was called and returned,
+// by a clock shared between the callers.
+type memoEvent struct {
+	op        memoOp
+	arg       int
+	result    int
+	call, ret int64
+}
+
+func (e memoEvent) String() string {
+	return fmt.Sprintf("%v(%d)=%d@[%d,%d]", e.op, e.arg, e.result, e.call, e.ret)
+}
+
+// memoModel is the sequential specification of a MemoizingPrimer with the default options:
+// given what it has memoized up to, it returns what each call memoizes up to afterwards, and
+// what a snapshot returns.
+func memoModel(memoized int, e memoEvent) (next, result int) {
+	switch e.op {
+	case opGrow, opPrimesUpTo:
+		return max(memoized, e.arg), 0
+	case opIsPrime:
+		if e.arg > 2 && e.arg%2 == 1 {
+			return max(memoized, e.arg), 0
+		}
+	case opShrink:
+		if e.arg < memoized {
+			// Below 10, Shrink resets p.
+			return max(e.arg, 10), 0
+		}
+	case opSnapshot:
+		return memoized, memoized
+	}
+	return memoized, 0
+}
+
+// linearizable returns whether history, of up to 64 events, can be ordered so that each event
+// takes effect at some point between its call and return, with the results memoModel gives
+// from an initial max of init. It's a backtracking search, after Wing and Gong, skipping states
+// it has already ruled out.
+func linearizable(history []memoEvent, init int) bool {
+	type state struct {
+		max  int
+		done uint64
+	}
+	all := uint64(1)<<len(history) - 1
+	dead := make(map[state]bool)
+	var search func(s state) bool
+	search = func(s state) bool {
+		if s.done == all {
+			return true
+		}
+		if dead[s] {
+			return false
+		}
+		// An event can go next only if it was called before every other pending event returned.
+		firstRet := int64(-1)
+		for i, e := range history {
+			if s.done&(1<<i) == 0 && (firstRet < 0 || e.ret < firstRet) {
+				firstRet = e.ret
+			}
+		}
+		for i, e := range history {
+			if s.done&(1<<i) != 0 || e.call > firstRet {
+				continue
+			}
+			next, result := memoModel(s.max, e)
+			if result != e.result {
+				continue
+			}
+			if search(state{next, s.done | 1<<i}) {
+				return true
+			}
+		}
+		dead[s] = true
+		return false
+	}
+	return search(state{init, 0})
+}
+
+// memoCaller makes calls on a MemoizingPrimer, checking each result against the oracle, and
+// records them.
+type memoCaller struct {
+	t     *testing.T
+	p     *MemoizingPrimer
+	clock *int64
+}
+
+// do makes the call e describes, and returns it with its result and timing filled in.
+func (c memoCaller) do(e memoEvent) memoEvent {
+	e.call = atomic.AddInt64(c.clock, 1)
+	switch e.op {
+	case opGrow:
+		if err := c.p.computeUpTo(e.arg); err != nil {
+			c.t.Errorf("computeUpTo(%d): %v", e.arg, err)
+		}
+	case opShrink:
+		c.p.Shrink(e.arg)
+	case opIsPrime:
+		if got, want := c.p.IsPrime(e.arg), oracle(e.arg); got != want {
+			c.t.Errorf("IsPrime(%d): got: %v want: %v", e.arg, got, want)
+		}
+	case opPrimesUpTo:
+		checkUpTo(c.t, "Memo", e.arg, PrimesUpTo(e.arg, c.p))
+	case opSnapshot:
+		e.result = c.snapshot()
+	}
+	e.ret = atomic.AddInt64(c.clock, 1)
+	return e
+}
+
+// snapshot returns how far p has memoized, after checking that its table holds exactly the
+// primes up to there.
+func (c memoCaller) snapshot() int {
+	c.p.lock.RLock()
+	defer c.p.lock.RUnlock()
+	max := int(c.p.max)
+	k := 0
+	for n := 2; n <= max; n++ {
+		if !oracle(n) {
+			continue
+		}
+		if k >= c.p.listed.len() || c.p.listed.at(k) != n {
+			c.t.Errorf("table up to %d: prime #%d is %d, but the table has %d primes", max, k+1, n, c.p.listed.len())
+			return max
+		}
+		k++
+	}
+	if k != c.p.listed.len() {
+		c.t.Errorf("table up to %d: has %d primes, want %d", max, c.p.listed.len(), k)
+	}
+	return max
+}
+
+// randomEvent returns a call to make, with an argument up to top.
+func randomEvent(r *rand.Rand, top int) memoEvent {
+	return memoEvent{op: memoOp(r.Intn(int(numMemoOps))), arg: r.Intn(top+3) - 2}
+}
+
+// TestMemoizingPrimerStress hammers one MemoizingPrimer with growing, shrinking and queries from
+// many goroutines at once, checking every answer, and that the table stays consistent.
+// Run it with -race.
+func TestMemoizingPrimerStress(t *testing.T) {
+	goroutines, calls := 16, 500
+	if testing.Short() {
+		calls = 50
+	}
+	p := NewMemoizingPrimer()
+	c := memoCaller{t: t, p: p, clock: new(int64)}
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(int64(g)))
+			for i := 0; i < calls; i++ {
+				c.do(randomEvent(r, fuzzMax))
+			}
+		}(g)
+	}
+	wg.Wait()
+	c.snapshot()
+}
+
+// TestMemoizingPrimerLinearizable records short histories of concurrent calls on fresh
+// MemoizingPrimers, and checks that each is linearizable: that what each snapshot saw is
+// consistent with the calls taking effect one at a time, in an order that respects which
+// returned before which were called.
+func TestMemoizingPrimerLinearizable(t *testing.T) {
+	const goroutines, calls = 4, 8 // at most 64 events per history
+	rounds := 500
+	if testing.Short() {
+		rounds = 50
+	}
+	for round := 0; round < rounds; round++ {
+		c := memoCaller{t: t, p: NewMemoizingPrimer(), clock: new(int64)}
+		histories := make([][]memoEvent, goroutines)
+		var wg sync.WaitGroup
+		for g := 0; g < goroutines; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				r := rand.New(rand.NewSource(int64(round*goroutines + g)))
+				for i := 0; i < calls; i++ {
+					// Small arguments, so that calls often overlap on the same part of the table.
+					histories[g] = append(histories[g], c.do(randomEvent(r, 3000)))
+				}
+			}(g)
+		}
+		wg.Wait()
+
+		var history []memoEvent
+		for _, h := range histories {
+			history = append(history, h...)
+		}
+		if !linearizable(history, 10) {
+			t.Fatalf("round %d: history is not linearizable: %v", round, history)
+		}
+	}
+}
+
+func TestLinearizable(t *testing.T) {
+	// The checker itself: a snapshot that saw a Shrink that hadn't been called yet is wrong,
+	// but one that overlapped it may go either way.
+	grow := memoEvent{op: opGrow, arg: 100, call: 1, ret: 2}
+	for _, c := range []struct {
+		history []memoEvent
+		want    bool
+	}{
+		{[]memoEvent{grow, {op: opSnapshot, result: 100, call: 3, ret: 4}}, true},
+		{[]memoEvent{grow, {op: opSnapshot, result: 10, call: 3, ret: 4}}, false},
+		{[]memoEvent{grow, {op: opSnapshot, result: 10, call: 0, ret: 4}}, true},
+		{[]memoEvent{grow, {op: opShrink, arg: 50, call: 5, ret: 6}, {op: opSnapshot, result: 50, call: 3, ret: 4}}, false},
+		{[]memoEvent{grow, {op: opShrink, arg: 50, call: 3, ret: 6}, {op: opSnapshot, result: 50, call: 4, ret: 5}}, true},
+		{[]memoEvent{grow, {op: opShrink, arg: 50, call: 3, ret: 6}, {op: opSnapshot, result: 100, call: 4, ret: 5}}, true},
+		{[]memoEvent{{op: opShrink, arg: 3, call: 1, ret: 2}, {op: opSnapshot, result: 10, call: 3, ret: 4}}, true},
+		{[]memoEvent{{op: opIsPrime, arg: 20, call: 1, ret: 2}, {op: opSnapshot, result: 10, call: 3, ret: 4}}, true},
+		{[]memoEvent{{op: opIsPrime, arg: 21, call: 1, ret: 2}, {op: opSnapshot, result: 10, call: 3, ret: 4}}, false},
+	} {
+		if got := linearizable(c.history, 10); got != c.want {
+			t.Errorf("for %v: got: %v want: %v", c.history, got, c.want)
+		}
+	}
+}
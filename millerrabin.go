@@ -0,0 +1,69 @@
+package primes
+
+import (
+	"math/bits"
+)
+
+// millerRabinBases are witnesses that make the Miller-Rabin test deterministic for all n < 2^64.
+// (Checking the first twelve primes suffices for n < 3.3 * 10^24.)
+var millerRabinBases = []uint64{2, 3, 5, 7, 11, 13, 17, 19, 23, 29, 31, 37}
+
+// millerRabin tests whether n is prime, using a deterministic Miller-Rabin test.
+// Unlike the sieves, it takes constant memory and roughly log(n) time, regardless of n.
+func millerRabin(n int) bool {
+	if n < 2 {
+		return false
+	}
+	m := uint64(n)
+	for _, b := range millerRabinBases {
+		if m == b {
+			return true
+		}
+		if m%b == 0 {
+			return false
+		}
+	}
+
+	// Write n-1 as d * 2^s, with d odd.
+	d := m - 1
+	s := bits.TrailingZeros64(d)
+	d >>= uint(s)
+
+	for _, b := range millerRabinBases {
+		x := powMod(b, d, m)
+		if x == 1 || x == m-1 {
+			continue
+		}
+		composite := true
+		for r := 1; r < s; r++ {
+			x = mulMod(x, x, m)
+			if x == m-1 {
+				composite = false
+				break
+			}
+		}
+		if composite {
+			return false
+		}
+	}
+	return true
+}
+
+// mulMod returns (a * b) mod m, without overflowing. a and b must be less than m.
+func mulMod(a, b, m uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return bits.Rem64(hi, lo, m)
+}
+
+// powMod returns (b ^ e) mod m.
+func powMod(b, e, m uint64) uint64 {
+	result := uint64(1)
+	b %= m
+	for ; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			result = mulMod(result, b, m)
+		}
+		b = mulMod(b, b, m)
+	}
+	return result
+}
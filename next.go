@@ -0,0 +1,34 @@
+package primes
+
+// NextPrime returns the smallest prime greater than n. ok is false if that's beyond math.MaxInt.
+// It uses Miller-Rabin, rather than a Primer, so it's quick for any n.
+func NextPrime(n int) (prime int, ok bool) {
+	if n < 2 {
+		return 2, true
+	}
+	// Step through the odd numbers after n, until they overflow.
+	for m := n + 1 + n%2; m > 0; m += 2 {
+		if millerRabin(m) {
+			return m, true
+		}
+	}
+	return 0, false
+}
+
+// PrevPrime returns the largest prime less than n. ok is false if there isn't one, i.e. n <= 2.
+// Like NextPrime, it uses Miller-Rabin.
+func PrevPrime(n int) (prime int, ok bool) {
+	if n <= 2 {
+		return 0, false
+	}
+	if n == 3 {
+		return 2, true
+	}
+	// Step through the odd numbers before n.
+	for m := n - 1 - n%2; m >= 3; m -= 2 {
+		if millerRabin(m) {
+			return m, true
+		}
+	}
+	return 2, true
+}
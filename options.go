@@ -0,0 +1,45 @@
+package primes
+
+// Option configures a Primer at construction, e.g. NewMemoizingPrimer(WithMaxMemory(256<<20)).
+type Option func(*options)
+
+// options collects the settings that Options can change.
+type options struct {
+	// maxMemory is the most memory, in bytes, that a Primer may use for its sieve and tables.
+	maxMemory int
+	// maxMemoized is the largest number a memoizing Primer may store results up to.
+	maxMemoized int
+	// thresholds are where Auto switches algorithms.
+	thresholds Thresholds
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		maxMemory:   DefaultMaxMemory,
+		maxMemoized: MaxN,
+		thresholds:  DefaultThresholds,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithMaxMemory limits the memory that a Primer uses for sieving and for storing primes to
+// about `bytes` bytes. Within the limit, Primers sieve in segments rather than all at once;
+// PrimesUpToE reports requests that can't be met within the limit as ErrOutOfMemoryBudget.
+func WithMaxMemory(bytes int) Option {
+	return func(o *options) {
+		o.maxMemory = bytes
+	}
+}
+
+// WithMaxMemoized limits a memoizing Primer to remembering the primes up to max.
+// Beyond that, it answers IsPrime with a Miller-Rabin test, and streams PrimesUpTo from a
+// segmented sieve, rather than growing its table. Use it to keep a single large query from
+// pinning memory for the Primer's lifetime.
+func WithMaxMemoized(max int) Option {
+	return func(o *options) {
+		o.maxMemoized = max
+	}
+}
@@ -0,0 +1,43 @@
+package primes
+
+// presievePrimes are the small odd primes whose multiples are crossed off by copying
+// presievePattern, rather than by sieving.
+var presievePrimes = []int{3, 5, 7, 11, 13}
+
+// presieveMax is the largest of presievePrimes; sieves can skip crossing off multiples of any
+// prime up to and including it.
+const presieveMax = 13
+
+// presievePattern is the composite-ness of odd numbers, as far as presievePrimes are concerned.
+// It uses the same odds-only layout as the sieves: index i refers to the number (i*2)+1.
+// Its length is the product of presievePrimes, so the pattern repeats exactly.
+var presievePattern = func() []bool {
+	period := 1
+	for _, p := range presievePrimes {
+		period *= p
+	}
+	pattern := make([]bool, period)
+	for _, p := range presievePrimes {
+		// Odd multiples of p (including p itself) are 2p apart, i.e. p indices apart.
+		for i := (p - 1) / 2; i < period; i += p {
+			pattern[i] = true
+		}
+	}
+	return pattern
+}()
+
+// presieve initializes an odds-only composite slice by tiling presievePattern over it.
+// composite[k] refers to the number ((offset+k)*2)+1; i.e. offset is the index of the slice's
+// first element within the full odds-only layout. Afterwards, all multiples of presievePrimes
+// are marked as composite- except for presievePrimes themselves.
+func presieve(composite []bool, offset int) {
+	// The first copy may start partway through the pattern; the rest are whole.
+	for i := copy(composite, presievePattern[offset%len(presievePattern):]); i < len(composite); i += len(presievePattern) {
+		copy(composite[i:], presievePattern)
+	}
+	for _, p := range presievePrimes {
+		if i := (p-1)/2 - offset; 0 <= i && i < len(composite) {
+			composite[i] = false
+		}
+	}
+}
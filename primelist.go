@@ -0,0 +1,86 @@
+package primes
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// intSize is the size of an int, in bytes.
+const intSize = bits.UintSize / 8
+
+// primeList is an ascending list of primes.
+// Primes that fit in 32 bits- which is all of them, for a table up to 2^32- are stored as uint32,
+// which takes half the memory of an []int on 64-bit platforms. Larger primes spill over into an
+// []int.
+type primeList struct {
+	small []uint32
+	large []int
+}
+
+func newPrimeList(primes ...int) primeList {
+	var l primeList
+	for _, p := range primes {
+		l.append(p)
+	}
+	return l
+}
+
+// append adds n to the end of the list. n must be larger than every prime already in the list.
+func (l *primeList) append(n int) {
+	if len(l.large) == 0 && uint64(n) <= math.MaxUint32 {
+		l.small = append(l.small, uint32(n))
+		return
+	}
+	l.large = append(l.large, n)
+}
+
+// reserve grows the list's capacity to hold all the primes up to n, by piUpperBound, so that
+// appending them doesn't reallocate (and, on the way, hold both the old and new arrays).
+func (l *primeList) reserve(n int) {
+	if want := piUpperBound(min(n, math.MaxUint32)); len(l.large) == 0 && want > cap(l.small) {
+		small := make([]uint32, len(l.small), want)
+		copy(small, l.small)
+		l.small = small
+	}
+	if want := piUpperBound(n) - len(l.small); n > math.MaxUint32 && want > cap(l.large) {
+		large := make([]int, len(l.large), want)
+		copy(large, l.large)
+		l.large = large
+	}
+}
+
+// len returns the number of primes in the list.
+func (l *primeList) len() int {
+	return len(l.small) + len(l.large)
+}
+
+// bytes returns the (approximate) memory used by the list's elements, in bytes.
+func (l *primeList) bytes() int {
+	return 4*cap(l.small) + intSize*cap(l.large)
+}
+
+// at returns the i'th prime in the list, counting from 0.
+func (l *primeList) at(i int) int {
+	if i < len(l.small) {
+		return int(l.small[i])
+	}
+	return l.large[i-len(l.small)]
+}
+
+// last returns the largest prime in the list.
+func (l *primeList) last() int {
+	return l.at(l.len() - 1)
+}
+
+// search returns the index of the first prime that is greater than or equal to n,
+// or l.len() if there is no such prime. It's the primeList analogue of sort.SearchInts.
+func (l *primeList) search(n int) int {
+	return sort.Search(l.len(), func(i int) bool { return l.at(i) >= n })
+}
+
+// contains returns whether n is in the list.
+func (l *primeList) contains(n int) bool {
+	i := l.search(n)
+	return i < l.len() && l.at(i) == n
+}
@@ -2,18 +2,17 @@
 package primes
 
 import (
+	"fmt"
 	"math"
+	"unsafe"
 )
 
 var (
-	Implementations = map[string]Primer{
-		"SimpleErat": &simpleErat{},
-		"Erat2":      &erat2{},
-		"Erat3":      &erat3{},
-		"Erat4":      &erat4{},
-		"Erat5":      &erat5{},
-		"Memo":				NewMemoizingPrimer(),
-	}
+	// Implementations holds an instance of each of the built-in Primers, by name.
+	//
+	// Deprecated: the instances are shared, so e.g. everyone using "Memo" shares its table.
+	// Use Names and Lookup, which make fresh instances and describe what they can do.
+	Implementations = map[string]Primer{}
 )
 
 // TODO use math/big?
@@ -29,20 +28,103 @@ type Primer interface {
 	IsPrime(n int) bool
 }
 
+// maxBuffer caps the channel buffer used by PrimesUpTo.
+const maxBuffer = 1 << 16
+
+// Integer is the set of integer types accepted by the typed helpers, e.g. PrimesUpTo.
+// Primers themselves work in terms of int; the helpers convert at the edges.
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// asInt converts n to an int, saturating at math.MaxInt if it doesn't fit.
+func asInt[T Integer](n T) int {
+	if n > 0 && uint64(n) > math.MaxInt {
+		return math.MaxInt
+	}
+	return int(n)
+}
+
 // Alternative, easier to test: collect into an array.
-func PrimesUpTo(n int, p Primer) []int {
+// The result has the same type as n, e.g. PrimesUpTo(uint32(1<<20), p) returns a []uint32.
+// Every prime up to n fits in n's type, so the conversion is lossless.
+func PrimesUpTo[T Integer](n T, p Primer) []T {
+	m := asInt(n)
 	// Use pi(x) ~ x / log x to estimate capacity.
 	// OK to be only approximate for capacity; append will allocate more if needed.
-	est := n / int(math.Log(float64(n)))
+	est := 0
+	if m > 2 {
+		est = min(int(float64(m)/math.Log(float64(m))), maxBuffer)
+	}
 
-	result := []int{}
+	result := []T{}
 	c := make(chan int, est)
 	// allow buffering within the channel- don't block computation if we can help it.
 	// Not likely, since all we're doing is an append, but whatever.
 
-	go p.PrimesUpTo(n, c)
+	go p.PrimesUpTo(m, c)
 	for prime := range c {
-		result = append(result, prime)
+		result = append(result, T(prime))
 	}
 	return result
 }
+
+// PrimesUpToE is like PrimesUpTo, but checks n against p's limits first.
+// It returns ErrTooLarge if n is beyond MaxN, and ErrOutOfMemoryBudget if p, or the result,
+// would need more memory than p (or, by default, DefaultMaxMemory) allows.
+func PrimesUpToE[T Integer](n T, p Primer) ([]T, error) {
+	m := asInt(n)
+	if err := Check(m, p); err != nil {
+		return nil, err
+	}
+	if size, budget := int(unsafe.Sizeof(n)), memoryBudget(p); piUpperBound(m) > budget/size {
+		return nil, fmt.Errorf("%w: the primes up to %d need more than %d bytes", ErrOutOfMemoryBudget, m, budget)
+	}
+	return PrimesUpTo(n, p), nil
+}
+
+// Count returns pi(n), the number of primes up to n, as found by p. Unlike len(PrimesUpTo(n, p)),
+// it doesn't keep the primes, so it's limited only by what p can sieve; see Check.
+func Count(n int, p Primer) (int, error) {
+	if err := Check(n, p); err != nil {
+		return 0, err
+	}
+	c := make(chan int, maxBuffer)
+	go p.PrimesUpTo(n, c)
+	count := 0
+	for range c {
+		count++
+	}
+	return count, nil
+}
+
+// Nth returns the kth prime, as found by p; the first is 2.
+func Nth(k int, p Primer) (int, error) {
+	if k < 1 {
+		return 0, fmt.Errorf("primes: there's no prime number %d", k)
+	}
+	// Rosser's theorem: the kth prime is less than k (log k + log log k), for k >= 6.
+	bound := 11.0
+	if k >= 6 {
+		logK := math.Log(float64(k))
+		bound = float64(k) * (logK + math.Log(logK))
+	}
+	if bound > MaxN {
+		return 0, fmt.Errorf("%w: prime number %d is beyond %d", ErrTooLarge, k, MaxN)
+	}
+	n := int(bound)
+	if err := Check(n, p); err != nil {
+		return 0, err
+	}
+
+	c := make(chan int, maxBuffer)
+	go p.PrimesUpTo(n, c)
+	i, nth := 0, 0
+	for prime := range c {
+		// Keep draining, so that p can finish.
+		if i++; i == k {
+			nth = prime
+		}
+	}
+	return nth, nil
+}
@@ -2,8 +2,12 @@
 package primes
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"sync"
 	"reflect"
+	"runtime"
 	"testing"
 )
 
@@ -23,9 +27,23 @@ var (
 	}
 )
 
+// registered returns a fresh instance of each registered Primer whose Info matches, by name.
+func registered(match func(Info) bool) map[string]Primer {
+	primers := make(map[string]Primer)
+	for _, name := range Names() {
+		if factory, info, _ := Lookup(name); match(info) {
+			primers[name] = factory()
+		}
+	}
+	return primers
+}
+
+// all matches every Primer, for registered.
+func all(Info) bool { return true }
+
 func TestPrimesUpTo(t *testing.T) {
 	max := refPrimes[len(refPrimes)-1]
-	for name, p := range Implementations {
+	for name, p := range registered(all) {
 		got := PrimesUpTo(max, p)
 		if !reflect.DeepEqual(got, refPrimes) {
 			t.Errorf("Got incorrect result for Primer %s: got: %v wanted: %v",
@@ -39,11 +57,12 @@ func TestIsPrime(t *testing.T) {
 	max := refPrimes[len(refPrimes)-1]
 	min := -10
 	pointer := 0 // into refPrimes
+	primers := registered(all)
 
 	// Probably more efficient to keep the bigger loop on the outside.
 	for i := min; i < max; i++ {
 		want := i == refPrimes[pointer]
-		for name, p := range Implementations {
+		for name, p := range primers {
 			// i == refPrimes[pointer] means "is this prime".
 			got := p.IsPrime(i)
 			if got != want {
@@ -66,17 +85,19 @@ func TestIsPrimeParallel(t *testing.T) {
 	pointer := 0 // into refPrimes
 
 	var wg sync.WaitGroup
+	primers := registered(func(info Info) bool { return info.ThreadSafe })
 
 	// Probably more efficient to keep the bigger loop on the outside.
 	for i := min; i < max; i++ {
 		want := i == refPrimes[pointer]
-		for name, p := range Implementations {
+		for name, p := range primers {
 			// Idiomatic override of loop variables.
 			name := name
 			p := p
 			i := i
 			wg.Add(1)
 			go func() {
+				defer wg.Done()
 				// i == refPrimes[pointer] means "is this prime".
 				got := p.IsPrime(i)
 				if got != want {
@@ -90,7 +111,7 @@ func TestIsPrimeParallel(t *testing.T) {
 			pointer++
 		}
 	}
-	wg.Done()
+	wg.Wait()
 }
 
 func TestRegression(t *testing.T) {
@@ -156,3 +177,687 @@ func TestRegression(t *testing.T) {
 	// But: the case above succeeds!
 }
 
+
+func TestPresieve(t *testing.T) {
+	for _, offset := range []int{0, 1, 6, len(presievePattern) - 1, 5 * len(presievePattern)} {
+		composite := make([]bool, 3*len(presievePattern)+17)
+		presieve(composite, offset)
+		for i, got := range composite {
+			n := (offset+i)*2 + 1
+			want := false
+			for _, p := range presievePrimes {
+				if n != p && n%p == 0 {
+					want = true
+				}
+			}
+			if got != want {
+				t.Errorf("unexpected presieve result for %d (offset %d): got: %v want: %v", n, offset, got, want)
+			}
+		}
+	}
+}
+
+func TestPrimesUpToTyped(t *testing.T) {
+	max := refPrimes[len(refPrimes)-1]
+	p := &erat5{}
+
+	got32 := PrimesUpTo(uint32(max), p)
+	got64 := PrimesUpTo(int64(max), p)
+	for i, want := range refPrimes {
+		if int(got32[i]) != want || int(got64[i]) != want {
+			t.Errorf("unexpected prime #%d: got: %d (uint32), %d (int64) want: %d", i, got32[i], got64[i], want)
+		}
+	}
+}
+
+func TestPrimeList(t *testing.T) {
+	// Straddle the uint32 boundary.
+	want := []int{2, 3, 5, 4294967291, 4294967311, 4294967357}
+	l := newPrimeList(want...)
+	if len(l.small) != 4 || len(l.large) != 2 {
+		t.Errorf("unexpected storage: got: %d small, %d large want: 4 small, 2 large", len(l.small), len(l.large))
+	}
+	for i, w := range want {
+		if got := l.at(i); got != w {
+			t.Errorf("unexpected prime #%d: got: %d want: %d", i, got, w)
+		}
+		if !l.contains(w) {
+			t.Errorf("list does not contain %d", w)
+		}
+	}
+	if l.contains(4294967296) {
+		t.Errorf("list unexpectedly contains %d", 4294967296)
+	}
+}
+
+func TestPrimesUpToSmall(t *testing.T) {
+	for n := -2; n <= 4; n++ {
+		want := []int{}
+		for _, p := range refPrimes {
+			if p <= n {
+				want = append(want, p)
+			}
+		}
+		for name, p := range registered(all) {
+			got := PrimesUpTo(n, p)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Got incorrect result for Primer %s up to %d: got: %v wanted: %v",
+					name, n, got, want,
+				)
+			}
+		}
+	}
+}
+
+// FuzzPrimesUpToE checks that PrimesUpToE either errors cleanly or agrees with refPrimes-
+// in particular, near math.MaxInt, where the sieves' arithmetic could overflow.
+func FuzzPrimesUpToE(f *testing.F) {
+	for _, n := range []int{-1, 0, 1, 2, 3, 100, 1223, MaxN, MaxN + 1, math.MaxInt - 1, math.MaxInt, math.MinInt} {
+		f.Add(n)
+	}
+	max := refPrimes[len(refPrimes)-1]
+	f.Fuzz(func(t *testing.T, n int) {
+		if max < n && n <= 4*DefaultMaxMemory {
+			// Within budget, but too expensive to check here.
+			return
+		}
+		for name, p := range registered(all) {
+			got, err := PrimesUpToE(n, p)
+			if n > MaxN {
+				if !errors.Is(err, ErrTooLarge) {
+					t.Errorf("for Primer %s up to %d: got error: %v want: %v", name, n, err, ErrTooLarge)
+				}
+				continue
+			}
+			if n > 4*DefaultMaxMemory {
+				// No sieve fits that in the default budget.
+				if !errors.Is(err, ErrOutOfMemoryBudget) {
+					t.Errorf("for Primer %s up to %d: got error: %v want: %v", name, n, err, ErrOutOfMemoryBudget)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("for Primer %s up to %d: got error: %v want: nil", name, n, err)
+				continue
+			}
+			for i, p := range got {
+				if p != refPrimes[i] {
+					t.Errorf("for Primer %s up to %d: got prime #%d: %d want: %d", name, n, i, p, refPrimes[i])
+					break
+				}
+			}
+		}
+	})
+}
+
+func TestMaxMemory(t *testing.T) {
+	const n = 1000000
+	want := PrimesUpTo(n, &erat5{})
+
+	// Small enough that neither can hold all the primes up to n (nor sieve them in one go),
+	// but big enough for the primes up to sqrt(n). They can still stream them.
+	budget := WithMaxMemory(64 << 10)
+	for name, p := range map[string]Primer{
+		"Memo":      NewMemoizingPrimer(budget),
+		"Segmented": NewSegmentedErat(budget),
+	} {
+		if err := Check(n, p); err != nil {
+			t.Errorf("for Primer %s up to %d: got error: %v want: nil", name, n, err)
+		}
+		got := PrimesUpTo(n, p)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Got incorrect result for Primer %s up to %d: got %d primes want: %d",
+				name, n, len(got), len(want),
+			)
+		}
+		for _, c := range []struct {
+			n    int
+			want bool
+		}{{999983, true}, {999981, false}, {1000003, true}, {997 * 1009, false}} {
+			if got := p.IsPrime(c.n); got != c.want {
+				t.Errorf("for Primer %s on value %d: got: %v want: %v", name, c.n, got, c.want)
+			}
+		}
+	}
+
+	for name, p := range map[string]Primer{
+		"Memo":      NewMemoizingPrimer(WithMaxMemory(100)),
+		"Segmented": NewSegmentedErat(WithMaxMemory(100)),
+	} {
+		if _, err := PrimesUpToE(n, p); !errors.Is(err, ErrOutOfMemoryBudget) {
+			t.Errorf("for Primer %s up to %d: got error: %v want: %v", name, n, err, ErrOutOfMemoryBudget)
+		}
+	}
+}
+
+func TestPrimesUpToEBudget(t *testing.T) {
+	// There are 78498 primes up to 10^6, and 664579 up to 10^7: as ints, about 628KB and 5.3MB.
+	p := NewMemoizingPrimer(WithMaxMemory(1 << 20))
+	if got, err := PrimesUpToE(1000000, p); err != nil || len(got) != 78498 {
+		t.Errorf("up to 10^6: got %d primes, error: %v want: 78498, nil", len(got), err)
+	}
+	if _, err := PrimesUpToE(10000000, p); !errors.Is(err, ErrOutOfMemoryBudget) {
+		t.Errorf("up to 10^7: got error: %v want: %v", err, ErrOutOfMemoryBudget)
+	}
+	// The same result fits in the default budget.
+	if got, err := PrimesUpToE(10000000, NewMemoizingPrimer()); err != nil || len(got) != 664579 {
+		t.Errorf("up to 10^7 by default: got %d primes, error: %v want: 664579, nil", len(got), err)
+	}
+}
+
+func TestMillerRabin(t *testing.T) {
+	max := refPrimes[len(refPrimes)-1]
+	pointer := 0 // into refPrimes
+	for i := -10; i < max; i++ {
+		want := i == refPrimes[pointer]
+		if got := millerRabin(i); got != want {
+			t.Errorf("unexpected primacy for %d: got: %v want: %v", i, got, want)
+		}
+		if want {
+			pointer++
+		}
+	}
+
+	for _, c := range []struct {
+		n    int
+		want bool
+	}{
+		{561, false},                 // Carmichael number
+		{2047, false},                // strong pseudoprime to base 2
+		{3215031751, false},          // strong pseudoprime to bases 2, 3, 5, 7
+		{3825123056546413051, false}, // strong pseudoprime to bases 2 through 23
+		{2305843009213693951, true},  // 2^61 - 1
+		{9223372036854775783, true},  // largest prime below 2^63
+		{9223372036854775807, false}, // 2^63 - 1
+	} {
+		if got := millerRabin(c.n); got != c.want {
+			t.Errorf("unexpected primacy for %d: got: %v want: %v", c.n, got, c.want)
+		}
+	}
+}
+
+func TestMemoizingPrimerBounded(t *testing.T) {
+	const n = 100000
+	want := PrimesUpTo(n, &erat5{})
+
+	p := NewMemoizingPrimer(WithMaxMemoized(1000))
+	if got := PrimesUpTo(n, p); !reflect.DeepEqual(got, want) {
+		t.Errorf("Got incorrect result up to %d: got %d primes want: %d", n, len(got), len(want))
+	}
+	for _, c := range []struct {
+		n    int
+		want bool
+	}{{99991, true}, {99989 * 99991, false}, {2305843009213693951, true}} {
+		if got := p.IsPrime(c.n); got != c.want {
+			t.Errorf("for %d: got: %v want: %v", c.n, got, c.want)
+		}
+	}
+	if p.max > 1000 {
+		t.Errorf("memoized beyond bound: got: %d want: <= %d", p.max, 1000)
+	}
+}
+
+func TestMemoizingPrimerPeakMemory(t *testing.T) {
+	// Growing the table copies it, so for a moment both the old and the new table are live.
+	// At most, then, a call holds the table it started with, and everything it allocates.
+	const budget = 1 << 20
+	p := NewMemoizingPrimer(WithMaxMemory(budget))
+	// The second call grows the table again; the third would need about 430KB for the old table
+	// and 980KB for the new one, so it has to do without.
+	for _, n := range []int{1000003, 1200007, 2900017} {
+		table := p.listed.bytes()
+		var got bool
+		peak := uint64(table) + allocated(func() { got = p.IsPrime(n) })
+		if peak > budget {
+			t.Errorf("IsPrime(%d): peak memory up to %d bytes, want at most %d", n, peak, budget)
+		}
+		if want := millerRabin(n); got != want {
+			t.Errorf("IsPrime(%d): got: %v want: %v", n, got, want)
+		}
+	}
+	if p.max < 1200007 || p.max >= 2900017 {
+		t.Errorf("memoized up to %d, want from 1200007 to below 2900017", p.max)
+	}
+}
+
+func TestMemoizingPrimerMemory(t *testing.T) {
+	// Memoizing should take about the table, and a segment to sieve in: not a sieve up to n, or
+	// the spare capacity of a table grown by appending.
+	const n = 10000000
+	p := NewMemoizingPrimer()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	p.IsPrime(n + 19)
+	runtime.ReadMemStats(&after)
+
+	table := piUpperBound(n+19) * 4
+	if got, want := after.TotalAlloc-before.TotalAlloc, uint64(table+2*defaultSegmentSize); got > want {
+		t.Errorf("IsPrime(%d) allocated %d bytes, want at most %d", n+19, got, want)
+	}
+	// (Allowing for the allocator's rounding up.)
+	if got := p.listed.bytes(); got > table+table/64 {
+		t.Errorf("table for %d primes takes %d bytes, want at most %d", p.listed.len(), got, table)
+	}
+}
+
+func TestMemoizingPrimerShrink(t *testing.T) {
+	max := refPrimes[len(refPrimes)-1]
+	p := NewMemoizingPrimer()
+	p.IsPrime(max)
+
+	p.Shrink(100)
+	if p.max != 100 || p.listed.last() != 97 {
+		t.Errorf("after Shrink: got max %d, last prime %d want: max %d, last prime %d", p.max, p.listed.last(), 100, 97)
+	}
+	if got := PrimesUpTo(max, p); !reflect.DeepEqual(got, refPrimes) {
+		t.Errorf("Got incorrect result after Shrink: got: %v wanted: %v", got, refPrimes)
+	}
+
+	p.Reset()
+	if p.max != 10 || p.listed.len() != 4 {
+		t.Errorf("after Reset: got max %d, %d primes want: max %d, %d primes", p.max, p.listed.len(), 10, 4)
+	}
+	for _, n := range refPrimes {
+		if !p.IsPrime(n) {
+			t.Errorf("for %d after Reset: got: %v want: %v", n, false, true)
+		}
+	}
+}
+
+// benchLevels are the arguments to benchmark at, as in the benchmark command: 1..1 with 1 through
+// 5 zeros in the middle. Under -short, only the first three.
+func benchLevels() []int {
+	levels := []int{101, 1001, 10001, 100001, 1000001}
+	if testing.Short() {
+		return levels[:3]
+	}
+	return levels
+}
+
+// benchPrimer is a Primer to benchmark, named as in the benchmark command's results.
+type benchPrimer struct {
+	name string
+	// get returns the Primer to call. If cold, it's called (untimed) before every call, to get
+	// a fresh one.
+	get  func() Primer
+	cold bool
+}
+
+// benchPrimers returns each registered Primer to benchmark, in order of name. A stateful one
+// would answer every call after the first from its cache, so it's benchmarked both cold and warm:
+// as in the benchmark command, the warm variant keeps the plain name.
+func benchPrimers() []benchPrimer {
+	var r []benchPrimer
+	for _, name := range Names() {
+		factory, info, _ := Lookup(name)
+		p := factory()
+		if info.Stateful {
+			r = append(r,
+				benchPrimer{name: name + "/cold", get: func() Primer { return factory() }, cold: true},
+				benchPrimer{name: name, get: func() Primer { return p }},
+			)
+			continue
+		}
+		r = append(r, benchPrimer{name: name, get: func() Primer { return p }})
+	}
+	return r
+}
+
+// runBench times op on bp, after an untimed call to warm it up.
+func runBench(b *testing.B, bp benchPrimer, op func(Primer)) {
+	b.ReportAllocs()
+	p := bp.get()
+	op(p)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if bp.cold {
+			b.StopTimer()
+			p = bp.get()
+			b.StartTimer()
+		}
+		op(p)
+	}
+}
+
+func BenchmarkPrimesUpTo(b *testing.B) {
+	for _, bp := range benchPrimers() {
+		for _, n := range benchLevels() {
+			b.Run(fmt.Sprintf("%s/%d", bp.name, n), func(b *testing.B) {
+				runBench(b, bp, func(p Primer) {
+					c := make(chan int)
+					go p.PrimesUpTo(n, c)
+					for range c {
+					}
+				})
+			})
+		}
+	}
+}
+
+func BenchmarkIsPrime(b *testing.B) {
+	for _, bp := range benchPrimers() {
+		for _, level := range benchLevels() {
+			// As in the benchmark command, test the largest prime up to the level.
+			below := PrimesUpTo(level, &erat5{})
+			n := below[len(below)-1]
+			b.Run(fmt.Sprintf("%s/%d", bp.name, n), func(b *testing.B) {
+				runBench(b, bp, func(p Primer) {
+					p.IsPrime(n)
+				})
+			})
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	names := Names()
+	if len(names) != len(Implementations) {
+		t.Errorf("got %d registered Primers, but %d Implementations", len(names), len(Implementations))
+	}
+	for _, name := range names {
+		factory, info, ok := Lookup(name)
+		if !ok {
+			t.Errorf("Lookup(%q): not found", name)
+			continue
+		}
+		a, b := factory(), factory()
+		if info.Stateful && a == b {
+			t.Errorf("%s: factory made the same instance twice", name)
+		}
+		// Once a has answered, it should answer again from what it kept exactly when it's
+		// Stateful; and b, having kept nothing from a, should have to do the work itself.
+		const n = 100003
+		a.IsPrime(n)
+		warm, cold := allocated(func() { a.IsPrime(n) }), allocated(func() { b.IsPrime(n) })
+		if kept := warm < cold/2; kept != info.Stateful {
+			t.Errorf("%s: got Stateful %v, but IsPrime(%d) allocated %d bytes again, and %d on a fresh instance",
+				name, info.Stateful, n, warm, cold)
+		}
+		if err := Check(info.MaxN, a); err != nil {
+			t.Errorf("%s: Check(MaxN=%d): got error: %v", name, info.MaxN, err)
+		}
+		if info.MaxN < MaxN {
+			if err := Check(info.MaxN+1, a); err == nil {
+				t.Errorf("%s: Check(MaxN+1=%d): got no error", name, info.MaxN+1)
+			}
+		}
+		if info.Complexity.PrimesUpTo == "" || info.Complexity.IsPrime == "" {
+			t.Errorf("%s: missing Complexity: %+v", name, info.Complexity)
+		}
+	}
+	if _, _, ok := Lookup("NoSuchPrimer"); ok {
+		t.Errorf("Lookup of an unregistered name: got ok")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("registering a name twice: didn't panic")
+		}
+	}()
+	Register("Erat5", func() Primer { return &erat5{} }, Info{})
+}
+
+// allocated returns how many bytes f allocates.
+func allocated(f func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	f()
+	runtime.ReadMemStats(&after)
+	return after.TotalAlloc - before.TotalAlloc
+}
+
+func TestFactor(t *testing.T) {
+	for _, c := range []struct {
+		n    int
+		want []int
+	}{
+		{-5, nil}, {1, nil}, {2, []int{2}}, {12, []int{2, 2, 3}}, {1223, []int{1223}},
+		{1024 * 1021, []int{2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1021}},
+		{1000003 * 1000003, []int{1000003, 1000003}},
+		{4294967291 * 2147483647, []int{2147483647, 4294967291}},
+		{2305843009213693951, []int{2305843009213693951}},
+		{math.MaxInt64, []int{7, 7, 73, 127, 337, 92737, 649657}},
+	} {
+		if got := Factor(c.n); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Factor(%d): got: %v want: %v", c.n, got, c.want)
+		}
+	}
+	for n := 2; n < 10000; n++ {
+		product := 1
+		for _, f := range Factor(n) {
+			if !millerRabin(f) {
+				t.Errorf("Factor(%d): %d isn't prime", n, f)
+			}
+			product *= f
+		}
+		if product != n {
+			t.Errorf("Factor(%d): product of factors is %d", n, product)
+		}
+	}
+}
+
+func TestNextPrevPrime(t *testing.T) {
+	for i, p := range refPrimes[:len(refPrimes)-1] {
+		next := refPrimes[i+1]
+		for n := p; n < next; n++ {
+			if got, ok := NextPrime(n); !ok || got != next {
+				t.Errorf("NextPrime(%d): got: %d, %v want: %d, true", n, got, ok, next)
+			}
+			if got, ok := PrevPrime(n + 1); !ok || got != p {
+				t.Errorf("PrevPrime(%d): got: %d, %v want: %d, true", n+1, got, ok, p)
+			}
+		}
+	}
+	if got, ok := NextPrime(-7); !ok || got != 2 {
+		t.Errorf("NextPrime(-7): got: %d, %v want: 2, true", got, ok)
+	}
+	if _, ok := PrevPrime(2); ok {
+		t.Errorf("PrevPrime(2): got a prime")
+	}
+	// The largest prime that fits in an int64 is 2^63 - 25.
+	const largest = math.MaxInt64 - 24
+	if got, ok := NextPrime(largest - 1); !ok || got != largest {
+		t.Errorf("NextPrime(%d): got: %d, %v want: %d, true", largest-1, got, ok, largest)
+	}
+	if got, ok := NextPrime(largest); ok {
+		t.Errorf("NextPrime(%d): got: %d want: none", largest, got)
+	}
+	if got, ok := PrevPrime(math.MaxInt64); !ok || got != largest {
+		t.Errorf("PrevPrime(%d): got: %d, %v want: %d, true", int64(math.MaxInt64), got, ok, largest)
+	}
+}
+
+func TestCountNth(t *testing.T) {
+	max := refPrimes[len(refPrimes)-1]
+	for name, p := range registered(all) {
+		if got, err := Count(max, p); err != nil || got != len(refPrimes) {
+			t.Errorf("Count(%d) with %s: got: %d, %v want: %d", max, name, got, err, len(refPrimes))
+		}
+		for _, k := range []int{1, 2, 5, 6, 100, len(refPrimes)} {
+			if got, err := Nth(k, p); err != nil || got != refPrimes[k-1] {
+				t.Errorf("Nth(%d) with %s: got: %d, %v want: %d", k, name, got, err, refPrimes[k-1])
+			}
+		}
+	}
+	if _, err := Nth(0, &erat5{}); err == nil {
+		t.Errorf("Nth(0): got no error")
+	}
+	if _, err := Nth(math.MaxInt/2, &erat5{}); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Nth(%d): got: %v want: %v", math.MaxInt/2, err, ErrTooLarge)
+	}
+	if _, err := Count(1<<40, &erat5{}); !errors.Is(err, ErrOutOfMemoryBudget) {
+		t.Errorf("Count(%d): got: %v want: %v", 1<<40, err, ErrOutOfMemoryBudget)
+	}
+}
+
+func TestIsPrimeBatch(t *testing.T) {
+	// Dense queries near the start, with duplicates and non-positives; a dense window further up;
+	// and sparse outliers, out of order.
+	var ns []int
+	for n := -10; n < 3000; n++ {
+		ns = append(ns, n, n)
+	}
+	for n := 1 << 30; n < 1<<30+5000; n++ {
+		ns = append(ns, n)
+	}
+	ns = append(ns, math.MaxInt64, 2305843009213693951, 99989*99991, 1<<40+1, 1<<40-1)
+	for i := range ns {
+		j := (i * 7919) % len(ns)
+		ns[i], ns[j] = ns[j], ns[i]
+	}
+
+	got := IsPrimeBatch(ns)
+	if len(got) != len(ns) {
+		t.Fatalf("got %d answers for %d queries", len(got), len(ns))
+	}
+	for i, n := range ns {
+		if want := millerRabin(n); got[i] != want {
+			t.Errorf("for %d: got: %v want: %v", n, got[i], want)
+		}
+	}
+	if got := IsPrimeBatch(nil); len(got) != 0 {
+		t.Errorf("for no queries: got: %v", got)
+	}
+}
+
+func BenchmarkIsPrimeBatch(b *testing.B) {
+	// A million queries, spread evenly up to n: dense enough to be worth sieving up to 10^8,
+	// but too sparse beyond.
+	for _, n := range []int{10000000, 100000000, 1000000000, 10000000000000} {
+		ns := make([]int, 1000000)
+		for i := range ns {
+			ns[i] = (i*2654435761 + 12345) % n
+		}
+		b.Run(fmt.Sprintf("Batch/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				IsPrimeBatch(ns)
+			}
+		})
+		b.Run(fmt.Sprintf("MillerRabin/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for _, n := range ns {
+					millerRabin(n)
+				}
+			}
+		})
+	}
+}
+
+func TestAuto(t *testing.T) {
+	// Sizes either side of each threshold, including thresholds that leave a step out.
+	for _, th := range []Thresholds{
+		DefaultThresholds,
+		{TableMax: 0, SegmentMin: 0},
+		{TableMax: 100, SegmentMin: 1000},
+		{TableMax: 1001, SegmentMin: 1000},
+	} {
+		p := Auto(WithThresholds(th))
+		var want []int
+		c := make(chan int)
+		go (&erat5{}).PrimesUpTo(20000, c)
+		for x := range c {
+			want = append(want, x)
+		}
+		for _, n := range []int{-1, 0, 1, 2, 3, 99, 100, 101, 999, 1000, 1001, 20000} {
+			var got []int
+			c := make(chan int)
+			go p.PrimesUpTo(n, c)
+			for x := range c {
+				got = append(got, x)
+			}
+			k := 0
+			for k < len(want) && want[k] <= n {
+				k++
+			}
+			if !reflect.DeepEqual(got, want[:k]) && !(len(got) == 0 && k == 0) {
+				t.Errorf("PrimesUpTo(%d) with %+v: got: %v want: %v", n, th, got, want[:k])
+			}
+		}
+		for n := -1; n <= 20000; n++ {
+			if got, want := p.IsPrime(n), millerRabin(n); got != want {
+				t.Errorf("IsPrime(%d) with %+v: got: %v want: %v", n, th, got, want)
+			}
+		}
+	}
+	// Beyond the one-shot sieve's memory budget, Auto goes segmented rather than failing.
+	if err := Check(1<<36, Auto(WithMaxMemory(1<<20))); err != nil {
+		t.Errorf("Check(%d): %v", 1<<36, err)
+	}
+}
+
+func TestGaps(t *testing.T) {
+	for _, c := range []struct {
+		lo, hi int
+	}{{-5, 1223}, {2, 2}, {2, 3}, {3, 1223}, {4, 1222}, {24, 29}, {90, 97}, {114, 126}, {1000, 10}} {
+		var want []Gap
+		for i := 1; i < len(refPrimes); i++ {
+			if refPrimes[i-1] >= c.lo && refPrimes[i] <= c.hi {
+				want = append(want, Gap{Start: refPrimes[i-1], Length: refPrimes[i] - refPrimes[i-1]})
+			}
+		}
+		var got []Gap
+		for g := range Gaps(c.lo, c.hi) {
+			got = append(got, g)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Gaps(%d, %d): got: %v want: %v", c.lo, c.hi, got, want)
+		}
+
+		hist := GapHistogram(c.lo, c.hi)
+		wantHist := make(map[int]int)
+		for _, g := range want {
+			wantHist[g.Length]++
+		}
+		if !reflect.DeepEqual(hist, wantHist) {
+			t.Errorf("GapHistogram(%d, %d): got: %v want: %v", c.lo, c.hi, hist, wantHist)
+		}
+	}
+
+	// Across segments, against another sieve; and stopping early.
+	lo, hi := 1000001, 1000001+5*defaultSegmentSize
+	ref := PrimesUpTo(hi, &erat5{})
+	var want []Gap
+	for i := 1; i < len(ref); i++ {
+		if ref[i-1] >= lo {
+			want = append(want, Gap{Start: ref[i-1], Length: ref[i] - ref[i-1]})
+		}
+	}
+	var got []Gap
+	for g := range Gaps(lo, hi) {
+		got = append(got, g)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Gaps(%d, %d): got %d gaps want: %d", lo, hi, len(got), len(want))
+	}
+	count := 0
+	for range Gaps(2, math.MaxInt32) {
+		if count++; count == 3 {
+			break
+		}
+	}
+	if count != 3 {
+		t.Errorf("Gaps(2, %d): got %d gaps before stopping, want 3", math.MaxInt32, count)
+	}
+}
+
+func TestPrimesBetween(t *testing.T) {
+	for _, c := range []struct {
+		lo, hi int
+	}{{-5, 1223}, {2, 2}, {4, 4}, {24, 29}, {90, 97}, {1000, 10}} {
+		var want []int
+		for _, p := range refPrimes {
+			if c.lo <= p && p <= c.hi {
+				want = append(want, p)
+			}
+		}
+		var got []int
+		for p := range PrimesBetween(c.lo, c.hi) {
+			got = append(got, p)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("PrimesBetween(%d, %d): got: %v want: %v", c.lo, c.hi, got, want)
+		}
+	}
+}
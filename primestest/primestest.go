@@ -0,0 +1,218 @@
+// Package primestest is a conformance suite for implementations of primes.Primer.
+//
+// An implementation's tests call Run with a factory for it:
+//
+//	func TestConformance(t *testing.T) {
+//		primestest.Run(t, func() primes.Primer { return &myPrimer{} })
+//	}
+package primestest
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cceckman/primes"
+)
+
+// OracleMax is how far Run checks PrimesUpTo against its own sieve; with -short, only up to
+// ShortOracleMax.
+const (
+	OracleMax      = 10000000
+	ShortOracleMax = 100000
+)
+
+// stallTimeout is how long PrimesUpTo may go without sending a prime or closing its channel
+// before Run calls it stuck.
+const stallTimeout = 30 * time.Second
+
+// denseMax is how far Run checks IsPrime at every n, since many Primers sieve up to n to
+// answer it.
+const denseMax = 2000
+
+// Run checks the Primers that factory returns against the Primer contract:
+//   - PrimesUpTo sends the primes up to n in increasing order, then closes its channel, whether
+//     or not the channel is buffered; for n < 2, it sends nothing.
+//   - IsPrime is false for n < 2, and true for 2.
+//   - Both agree with a reference sieve up to OracleMax (or ShortOracleMax, with -short), where
+//     primes.Check allows.
+//   - IsPrime gives the same answers when called concurrently on one instance.
+//   - Neither leaves goroutines running once it has returned and its channel is drained.
+//
+// Each check gets a fresh instance from factory, and runs as a subtest.
+func Run(t *testing.T, factory primes.Factory) {
+	t.Helper()
+	before := runtime.NumGoroutine()
+
+	t.Run("EdgeCases", func(t *testing.T) { testEdgeCases(t, factory()) })
+	t.Run("Ordering", func(t *testing.T) { testOrdering(t, factory()) })
+	t.Run("Oracle", func(t *testing.T) { testOracle(t, factory()) })
+	t.Run("ConcurrentIsPrime", func(t *testing.T) { testConcurrentIsPrime(t, factory()) })
+	checkLeaks(t, before)
+}
+
+// collect returns what p.PrimesUpTo(n) sends on a channel with the given buffer. It fails t if
+// the channel stalls, without closing, for stallTimeout.
+func collect(t *testing.T, p primes.Primer, n, buffer int) []int {
+	t.Helper()
+	c := make(chan int, buffer)
+	go p.PrimesUpTo(n, c)
+	var got []int
+	timer := time.NewTimer(stallTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case v, ok := <-c:
+			if !ok {
+				return got
+			}
+			got = append(got, v)
+			timer.Reset(stallTimeout)
+		case <-timer.C:
+			t.Fatalf("PrimesUpTo(%d): stalled after %d values without closing its channel", n, len(got))
+		}
+	}
+}
+
+// sieve returns which numbers up to n are prime, by a plain sieve of Eratosthenes: deliberately
+// independent of the primes package.
+func sieve(n int) []bool {
+	prime := make([]bool, n+1)
+	for i := 2; i <= n; i++ {
+		prime[i] = true
+	}
+	for i := 2; i*i <= n; i++ {
+		if !prime[i] {
+			continue
+		}
+		for j := i * i; j <= n; j += i {
+			prime[j] = false
+		}
+	}
+	return prime
+}
+
+func testEdgeCases(t *testing.T, p primes.Primer) {
+	for _, n := range []int{-1, 0, 1} {
+		if got := collect(t, p, n, 0); len(got) != 0 {
+			t.Errorf("PrimesUpTo(%d): got: %v want: none", n, got)
+		}
+		if p.IsPrime(n) {
+			t.Errorf("IsPrime(%d): got: true want: false", n)
+		}
+	}
+	if got := collect(t, p, 2, 0); len(got) != 1 || got[0] != 2 {
+		t.Errorf("PrimesUpTo(2): got: %v want: [2]", got)
+	}
+	if !p.IsPrime(2) {
+		t.Errorf("IsPrime(2): got: false want: true")
+	}
+}
+
+func testOrdering(t *testing.T, p primes.Primer) {
+	// Repeat sizes, so that Primers with state answer some of them from it.
+	for _, n := range []int{3, 4, 100, 10, 1000, 97, 1000} {
+		for _, buffer := range []int{0, 1, 64} {
+			got := collect(t, p, n, buffer)
+			for i := range got {
+				if got[i] > n || (i > 0 && got[i] <= got[i-1]) {
+					t.Errorf("PrimesUpTo(%d), buffer %d: got %d at %d, after %v", n, buffer, got[i], i, got[max(i-3, 0):i])
+					break
+				}
+			}
+		}
+	}
+}
+
+func testOracle(t *testing.T, p primes.Primer) {
+	top := OracleMax
+	if testing.Short() {
+		top = ShortOracleMax
+	}
+	if err := primes.Check(top, p); err != nil {
+		t.Skipf("can't check up to %d: %v", top, err)
+	}
+	prime := sieve(top)
+
+	got := collect(t, p, top, 1024)
+	k := 0
+	for n := 2; n <= top; n++ {
+		if !prime[n] {
+			continue
+		}
+		if k >= len(got) || got[k] != n {
+			t.Fatalf("PrimesUpTo(%d): prime #%d is %d, but got: %v", top, k+1, n, got[k:min(k+3, len(got))])
+		}
+		k++
+	}
+	if k != len(got) {
+		t.Fatalf("PrimesUpTo(%d): got %d primes, then extra: %v", top, k, got[k:min(k+3, len(got))])
+	}
+
+	// IsPrime at every n up to denseMax, then on either side of a spread of primes up to top.
+	for n := -2; n <= denseMax; n++ {
+		if got, want := p.IsPrime(n), n >= 0 && prime[n]; got != want {
+			t.Errorf("IsPrime(%d): got: %v want: %v", n, got, want)
+		}
+	}
+	for i := len(got) - 1; i > 0 && got[i] > denseMax; i -= len(got)/4 + 1 {
+		for _, n := range []int{got[i] - 1, got[i], got[i] + 1, got[i] + 2} {
+			if n > top {
+				continue
+			}
+			if got, want := p.IsPrime(n), prime[n]; got != want {
+				t.Errorf("IsPrime(%d): got: %v want: %v", n, got, want)
+			}
+		}
+	}
+}
+
+func testConcurrentIsPrime(t *testing.T, p primes.Primer) {
+	const goroutines = 8
+	prime := sieve(denseMax)
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines)
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			// Interleave: each goroutine takes every goroutines'th n, from its own starting point,
+			// going up or down.
+			for i := 0; i <= denseMax; i++ {
+				n := (i*goroutines + g) % (denseMax + 1)
+				if g%2 == 1 {
+					n = denseMax - n
+				}
+				if got := p.IsPrime(n); got != prime[n] {
+					errs <- fmt.Errorf("IsPrime(%d) in goroutine %d: got: %v want: %v", n, g, got, prime[n])
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// checkLeaks checks that the goroutine count settles back to where it was before, i.e. that all
+// the goroutines the other checks started have exited.
+func checkLeaks(t *testing.T, before int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		now := runtime.NumGoroutine()
+		if now <= before {
+			return
+		}
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			buf = buf[:runtime.Stack(buf, true)]
+			t.Fatalf("%d goroutines left running (was %d):\n%s", now-before, before, buf)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,138 @@
+package primes
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Factory makes a new instance of a Primer.
+type Factory func() Primer
+
+// Info describes what a registered Primer can do, and at what cost.
+type Info struct {
+	// ThreadSafe is whether an instance may be called from several goroutines at once.
+	ThreadSafe bool
+	// Stateful is whether an instance keeps work from call to call (e.g. memoizes), so that
+	// how quickly it answers depends on what it's been asked before.
+	Stateful bool
+	// MaxN is the largest n that Check accepts for an instance, as its Factory makes it.
+	MaxN int
+	// Complexity is how the costs of the Primer's operations grow with n.
+	Complexity Complexity
+}
+
+// Complexity describes how the time and memory of a Primer's operations grow with n, e.g.
+// "O(n log log n) time, n/2 bytes".
+type Complexity struct {
+	PrimesUpTo string
+	IsPrime    string
+}
+
+type registration struct {
+	factory Factory
+	info    Info
+}
+
+var (
+	registryLock sync.RWMutex
+	registry     = make(map[string]registration)
+)
+
+// Register makes a Primer available by name, with a Factory to make instances of it.
+// It panics if the name is already registered, or if factory is nil.
+func Register(name string, factory Factory, info Info) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if factory == nil {
+		panic("primes: Register factory is nil")
+	}
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("primes: Register called twice for %q", name))
+	}
+	registry[name] = registration{factory: factory, info: info}
+}
+
+// Lookup returns the Factory and Info registered under name; ok is false if there are none.
+// Each call to the Factory makes a fresh instance, so callers don't share state.
+func Lookup(name string) (factory Factory, info Info, ok bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	r, ok := registry[name]
+	return r.factory, r.info, ok
+}
+
+// Names returns the names of the registered Primers, in order.
+func Names() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	// The plain sieves hold a bool per odd number up to n (simpleErat, per number) and no state;
+	// IsPrime(n) costs them as much as PrimesUpTo(n).
+	sieve := Complexity{PrimesUpTo: "O(n log log n) time, n/2 bytes", IsPrime: "as PrimesUpTo(n)"}
+	for name, factory := range map[string]Factory{
+		"Erat2": func() Primer { return &erat2{} },
+		"Erat3": func() Primer { return &erat3{} },
+		"Erat4": func() Primer { return &erat4{} },
+		"Erat5": func() Primer { return &erat5{} },
+		"Erat6": func() Primer { return &erat5{presieved: true} },
+	} {
+		Register(name, factory, Info{ThreadSafe: true, MaxN: largestChecked(factory()), Complexity: sieve})
+	}
+	Register("SimpleErat", func() Primer { return &simpleErat{} }, Info{
+		ThreadSafe: true,
+		MaxN:       largestChecked(&simpleErat{}),
+		Complexity: Complexity{PrimesUpTo: "O(n log log n) time, n bytes", IsPrime: "as PrimesUpTo(n)"},
+	})
+	Register("Memo", func() Primer { return NewMemoizingPrimer() }, Info{
+		ThreadSafe: true,
+		Stateful:   true,
+		MaxN:       largestChecked(NewMemoizingPrimer()),
+		Complexity: Complexity{
+			PrimesUpTo: "O(n log log n) time at first, then O(pi(n)); 4 bytes per prime up to 2^32, 8 beyond",
+			IsPrime:    "as PrimesUpTo(n) at first, then O(log n)",
+		},
+	})
+	Register("Segmented", func() Primer { return NewSegmentedErat() }, Info{
+		ThreadSafe: true,
+		MaxN:       largestChecked(NewSegmentedErat()),
+		Complexity: Complexity{PrimesUpTo: "O(n log log n) time, O(sqrt(n)) bytes", IsPrime: "as PrimesUpTo(n)"},
+	})
+	Register("Auto", func() Primer { return Auto() }, Info{
+		ThreadSafe: true,
+		MaxN:       largestChecked(Auto()),
+		Complexity: Complexity{
+			PrimesUpTo: "O(pi(n)) from its table up to Thresholds.TableMax; " +
+				"then, below Thresholds.SegmentMin, as Erat6 (O(n log log n) time, n/2 bytes); " +
+				"beyond, as Segmented (O(n log log n) time, O(sqrt(n)) bytes)",
+			IsPrime: "O(log n) up to Thresholds.TableMax; O(log^3 n) (Miller-Rabin) beyond",
+		},
+	})
+
+	for _, name := range Names() {
+		factory, _, _ := Lookup(name)
+		Implementations[name] = factory()
+	}
+}
+
+// largestChecked returns the largest n that Check accepts for p, by binary search.
+func largestChecked(p Primer) int {
+	lo, hi := 0, MaxN // Check(lo, p) passes; look for the last that does, up to hi.
+	for lo < hi {
+		mid := lo + (hi-lo+1)/2
+		if Check(mid, p) == nil {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return lo
+}
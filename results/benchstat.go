@@ -0,0 +1,126 @@
+package results
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// benchName returns the `go test -bench`-style name of r's case, e.g. "BenchmarkIsPrime/Erat2/9973-4".
+func benchName(r Record) string {
+	name := fmt.Sprintf("Benchmark%s/%s/%d", r.Op, r.Impl, r.Arg)
+	if r.GOMAXPROCS > 1 {
+		name += fmt.Sprintf("-%d", r.GOMAXPROCS)
+	}
+	return name
+}
+
+// writeBenchstat writes records in the format of `go test -bench`, with a line per sample,
+// so that benchstat can compare them.
+func writeBenchstat(w io.Writer, records []Record) error {
+	if len(records) > 0 {
+		fmt.Fprintf(w, "pkg: github.com/cceckman/primes\n")
+		if cpu := records[0].CPU; cpu != "" {
+			fmt.Fprintf(w, "cpu: %s\n", cpu)
+		}
+	}
+	for _, r := range records {
+		samples := r.Samples
+		if len(samples) == 0 {
+			samples = []Sample{{Iterations: r.Iterations, NsPerOp: r.NsPerOp, BytesPerOp: r.BytesPerOp, AllocsPerOp: r.AllocsPerOp}}
+		}
+		for _, s := range samples {
+			_, err := fmt.Fprintf(w, "%s\t%d\t%s ns/op\t%d B/op\t%d allocs/op\n",
+				benchName(r), s.Iterations, formatFloat(s.NsPerOp), s.BytesPerOp, s.AllocsPerOp)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readBenchstat reads the format of `go test -bench`: a line per sample, with repeated lines for
+// the same case summarized into one Record. It ignores lines that aren't benchmark results.
+func readBenchstat(r io.Reader) ([]Record, error) {
+	var records []Record
+	index := make(map[Key]int) // into records
+	cpu := ""
+
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := s.Text()
+		if v, ok := strings.CutPrefix(line, "cpu:"); ok {
+			cpu = strings.TrimSpace(v)
+			continue
+		}
+		if !strings.HasPrefix(line, "Benchmark") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 4 || len(fields)%2 != 0 {
+			return nil, fmt.Errorf("line %d: malformed benchmark line %q", n, line)
+		}
+
+		// The name is Benchmark<Op>/<Impl>/<Arg>, possibly with a -<GOMAXPROCS> suffix.
+		name := strings.TrimPrefix(fields[0], "Benchmark")
+		procs := 1
+		if i := strings.LastIndex(name, "-"); i > strings.LastIndex(name, "/") {
+			if p, err := strconv.Atoi(name[i+1:]); err == nil {
+				name, procs = name[:i], p
+			}
+		}
+		// The implementation may itself have slashes, as in "Memo/cold".
+		parts := strings.Split(name, "/")
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("line %d: malformed benchmark name %q", n, fields[0])
+		}
+		arg, err := strconv.Atoi(parts[len(parts)-1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: malformed benchmark name %q: %v", n, fields[0], err)
+		}
+		key := Key{Op: parts[0], Impl: strings.Join(parts[1:len(parts)-1], "/"), Arg: arg}
+
+		var sample Sample
+		if sample.Iterations, err = strconv.Atoi(fields[1]); err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		for i := 2; i < len(fields); i += 2 {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", n, err)
+			}
+			switch fields[i+1] {
+			case "ns/op":
+				sample.NsPerOp = v
+			case "B/op":
+				sample.BytesPerOp = int64(v)
+			case "allocs/op":
+				sample.AllocsPerOp = int64(v)
+			}
+		}
+
+		i, ok := index[key]
+		if !ok {
+			i = len(records)
+			index[key] = i
+			records = append(records, Record{Op: key.Op, Impl: key.Impl, Arg: key.Arg})
+			records[i].GOMAXPROCS = procs
+			records[i].CPU = cpu
+		}
+		records[i].Samples = append(records[i].Samples, sample)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range records {
+		r := &records[i]
+		r.Stats, r.NsPerOp = Summarize(r.Samples)
+		last := r.Samples[len(r.Samples)-1]
+		r.Iterations, r.BytesPerOp, r.AllocsPerOp = last.Iterations, last.BytesPerOp, last.AllocsPerOp
+	}
+	return records, nil
+}
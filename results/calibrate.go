@@ -0,0 +1,67 @@
+package results
+
+import (
+	"io"
+	"sort"
+
+	"github.com/cceckman/primes"
+)
+
+// CalibrateThresholds reads benchmark results (in any format Read accepts) and returns
+// primes.DefaultThresholds with SegmentMin moved to where, in those results, the segmented sieve's
+// PrimesUpTo starts beating the one-shot sieve's (Erat6) for good. If the results don't say,
+// SegmentMin stays as it is.
+func CalibrateThresholds(r io.Reader) (primes.Thresholds, error) {
+	records, err := Read(r)
+	if err != nil {
+		return primes.Thresholds{}, err
+	}
+	t := primes.DefaultThresholds
+
+	// Pair up the two sieves' times, by argument.
+	type pair struct{ oneShot, segmented float64 }
+	pairs := make(map[int]*pair)
+	for _, rec := range records {
+		if rec.Op != "PrimesUpTo" || (rec.Impl != "Erat6" && rec.Impl != "Segmented") {
+			continue
+		}
+		if pairs[rec.Arg] == nil {
+			pairs[rec.Arg] = &pair{}
+		}
+		if rec.Impl == "Erat6" {
+			pairs[rec.Arg].oneShot = rec.NsPerOp
+		} else {
+			pairs[rec.Arg].segmented = rec.NsPerOp
+		}
+	}
+	var args []int
+	for arg, p := range pairs {
+		if p.oneShot > 0 && p.segmented > 0 {
+			args = append(args, arg)
+		}
+	}
+	if len(args) == 0 {
+		return t, nil
+	}
+	sort.Ints(args)
+
+	// Walk down from the largest argument, for as long as the segmented sieve wins.
+	segmentMin := -1
+	for i := len(args) - 1; i >= 0; i-- {
+		if p := pairs[args[i]]; p.segmented > p.oneShot {
+			break
+		}
+		segmentMin = args[i]
+	}
+	switch {
+	case segmentMin == args[0]:
+		// The segmented sieve won throughout.
+		t.SegmentMin = 0
+	case segmentMin > 0:
+		t.SegmentMin = segmentMin
+	default:
+		// The one-shot sieve won even at the largest argument; switch just past it.
+		t.SegmentMin = args[len(args)-1] + 1
+	}
+	return t, nil
+}
@@ -0,0 +1,52 @@
+package results
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cceckman/primes"
+)
+
+func TestCalibrateThresholds(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  int
+	}{
+		{"crossover", `
+BenchmarkPrimesUpTo/Erat6/1000	1	100 ns/op	0 B/op	0 allocs/op
+BenchmarkPrimesUpTo/Segmented/1000	1	200 ns/op	0 B/op	0 allocs/op
+BenchmarkPrimesUpTo/Erat6/10000	1	1000 ns/op	0 B/op	0 allocs/op
+BenchmarkPrimesUpTo/Segmented/10000	1	900 ns/op	0 B/op	0 allocs/op
+BenchmarkPrimesUpTo/Erat6/100000	1	9000 ns/op	0 B/op	0 allocs/op
+BenchmarkPrimesUpTo/Segmented/100000	1	9500 ns/op	0 B/op	0 allocs/op
+BenchmarkPrimesUpTo/Erat6/1000000	1	100000 ns/op	0 B/op	0 allocs/op
+BenchmarkPrimesUpTo/Segmented/1000000	1	90000 ns/op	0 B/op	0 allocs/op
+BenchmarkPrimesUpTo/Segmented/10000000	1	900000 ns/op	0 B/op	0 allocs/op
+BenchmarkIsPrime/Erat6/10000000	1	1 ns/op	0 B/op	0 allocs/op
+`, 1000000},
+		{"segmented throughout", `
+BenchmarkPrimesUpTo/Erat6/1000	1	300 ns/op	0 B/op	0 allocs/op
+BenchmarkPrimesUpTo/Segmented/1000	1	200 ns/op	0 B/op	0 allocs/op
+`, 0},
+		{"one-shot throughout", `
+BenchmarkPrimesUpTo/Erat6/1000	1	100 ns/op	0 B/op	0 allocs/op
+BenchmarkPrimesUpTo/Segmented/1000	1	200 ns/op	0 B/op	0 allocs/op
+`, 1001},
+		{"no comparison", `
+BenchmarkPrimesUpTo/Erat5/1000	1	100 ns/op	0 B/op	0 allocs/op
+`, primes.DefaultThresholds.SegmentMin},
+	} {
+		got, err := CalibrateThresholds(strings.NewReader(strings.TrimPrefix(tc.input, "\n")))
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if got.SegmentMin != tc.want || got.TableMax != primes.DefaultThresholds.TableMax {
+			t.Errorf("%s: got: %+v want: SegmentMin %d", tc.name, got, tc.want)
+		}
+	}
+	if _, err := CalibrateThresholds(strings.NewReader("BenchmarkPrimesUpTo 1 2\n")); err == nil {
+		t.Errorf("malformed results: got no error")
+	}
+}
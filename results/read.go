@@ -0,0 +1,179 @@
+package results
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Key identifies a benchmark case, independent of when or where it ran.
+type Key struct {
+	Op   string
+	Impl string
+	Arg  int
+}
+
+// Key returns the Key of the case r records.
+func (r Record) Key() Key {
+	return Key{Op: r.Op, Impl: r.Impl, Arg: r.Arg}
+}
+
+var nameRE = regexp.MustCompile(`^(\w+): (.+)\((-?\d+)\)$`)
+
+// ParseName splits a name like "IsPrime: Erat2(9973)", as returned by Record.Name, into its Key.
+func ParseName(name string) (Key, error) {
+	m := nameRE.FindStringSubmatch(name)
+	if m == nil {
+		return Key{}, fmt.Errorf("malformed case name %q", name)
+	}
+	arg, err := strconv.Atoi(m[3])
+	if err != nil {
+		return Key{}, fmt.Errorf("malformed case name %q: %v", name, err)
+	}
+	return Key{Op: m[1], Impl: m[2], Arg: arg}, nil
+}
+
+// Read reads records in any of the Formats that Write writes, detecting which from the content.
+// (For Benchstat, it reads back the Samples, but not the rest of the Env.)
+// It also reads the TSV written by github.com/cceckman/bencher, e.g. the original results.tsv,
+// which only has the first seven TSV columns.
+func Read(r io.Reader) ([]Record, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(1)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if head[0] == '[' {
+		var records []Record
+		err := json.NewDecoder(br).Decode(&records)
+		return records, err
+	}
+
+	line, err := br.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	rest := io.MultiReader(strings.NewReader(line), br)
+	switch {
+	case strings.HasPrefix(line, "Name\t"):
+		return readTable(rest, '\t')
+	case strings.HasPrefix(line, "op,"):
+		return readTable(rest, ',')
+	}
+	return readBenchstat(rest)
+}
+
+// readTable reads a CSV or TSV table, with a header row naming the columns.
+func readTable(r io.Reader, sep rune) ([]Record, error) {
+	in := csv.NewReader(r)
+	in.Comma = sep
+	in.FieldsPerRecord = -1
+	if sep == '\t' {
+		in.LazyQuotes = true
+	}
+	rows, err := in.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	// Map column names, as written in either header, to their indices.
+	cols := make(map[string]int)
+	for i, name := range rows[0] {
+		cols[strings.TrimSpace(name)] = i
+	}
+	get := func(row []string, names ...string) string {
+		for _, name := range names {
+			if i, ok := cols[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+		}
+		return ""
+	}
+
+	var records []Record
+	for n, row := range rows[1:] {
+		if len(row) == 0 || (len(row) == 1 && row[0] == "") {
+			continue
+		}
+		var rec Record
+		var errs []error
+		parseInt := func(s string) int64 {
+			if s == "" {
+				return 0
+			}
+			v, err := strconv.ParseInt(s, 10, 64)
+			errs = append(errs, err)
+			return v
+		}
+		parseFloat := func(s string) float64 {
+			if s == "" {
+				return 0
+			}
+			v, err := strconv.ParseFloat(s, 64)
+			errs = append(errs, err)
+			return v
+		}
+
+		if name := get(row, "Name"); name != "" {
+			key, err := ParseName(name)
+			errs = append(errs, err)
+			rec.Op, rec.Impl, rec.Arg = key.Op, key.Impl, key.Arg
+		} else {
+			rec.Op = get(row, "op", "Op")
+			rec.Impl = get(row, "impl", "Impl")
+			rec.Arg = int(parseInt(get(row, "arg", "Arg")))
+		}
+		rec.Result = get(row, "result", "Result")
+		rec.Iterations = int(parseInt(get(row, "iterations", "Iterations")))
+		rec.NsPerOp = parseFloat(get(row, "ns_per_op", "Avg time (ns)"))
+		rec.BytesPerOp = parseInt(get(row, "bytes_per_op", "Avg memory (bytes)"))
+		rec.AllocsPerOp = parseInt(get(row, "allocs_per_op", "Avg allocs (ops)"))
+		rec.GoVersion = get(row, "go_version", "Go version")
+		rec.GOMAXPROCS = int(parseInt(get(row, "gomaxprocs", "GOMAXPROCS")))
+		rec.CPU = get(row, "cpu", "CPU")
+		rec.Stats = Stats{
+			Samples: int(parseInt(get(row, "samples", "Samples"))),
+			Median:  parseFloat(get(row, "median_ns", "Median (ns)")),
+			Min:     parseFloat(get(row, "min_ns", "Min (ns)")),
+			Max:     parseFloat(get(row, "max_ns", "Max (ns)")),
+			Stddev:  parseFloat(get(row, "stddev_ns", "Stddev (ns)")),
+			CILow:   parseFloat(get(row, "ci95_low_ns", "95% CI low (ns)")),
+			CIHigh:  parseFloat(get(row, "ci95_high_ns", "95% CI high (ns)")),
+		}
+		rec.Detail = Detail{
+			GCs:             parseInt(get(row, "gcs", "GCs")),
+			GCPauseNs:       parseInt(get(row, "gc_pause_ns", "GC pause (ns)")),
+			SieveBytesPerOp: parseInt(get(row, "sieve_bytes_per_op", "Sieve memory (bytes)")),
+			// Older files called OtherBytesPerOp "channel" memory.
+			OtherBytesPerOp: parseInt(get(row, "other_bytes_per_op", "Other memory (bytes)",
+				"channel_bytes_per_op", "Channel memory (bytes)")),
+			CacheMissesPerOp:  parseFloat(get(row, "cache_misses_per_op", "Cache misses/op")),
+			InstructionsPerOp: parseFloat(get(row, "instructions_per_op", "Instructions/op")),
+		}
+		if ts := get(row, "timestamp", "Timestamp"); ts != "" {
+			t, err := time.Parse(time.RFC3339, ts)
+			errs = append(errs, err)
+			rec.Timestamp = t
+		}
+
+		for _, err := range errs {
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %v", n+2, err)
+			}
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
@@ -0,0 +1,179 @@
+// Package results describes the results of benchmarking Primers, and writes them in
+// machine-readable formats.
+package results
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Record is the result of benchmarking one case: one operation, of one implementation,
+// on one argument.
+type Record struct {
+	// Op is the operation benchmarked, e.g. "PrimesUpTo" or "IsPrime".
+	Op string `json:"op"`
+	// Impl is the name of the implementation, as registered with primes.Register.
+	Impl string `json:"impl"`
+	// Arg is the argument passed to Op.
+	Arg int `json:"arg"`
+	// Result is the (stringified) result of the last call, as a sanity check.
+	Result string `json:"result"`
+
+	Iterations  int     `json:"iterations"`
+	NsPerOp     float64 `json:"ns_per_op"`
+	BytesPerOp  int64   `json:"bytes_per_op"`
+	AllocsPerOp int64   `json:"allocs_per_op"`
+
+	// Stats summarizes Samples, if the case was sampled more than once; NsPerOp is then their mean.
+	Stats   Stats    `json:"stats"`
+	Samples []Sample `json:"samples,omitempty"`
+
+	Detail Detail `json:"detail"`
+
+	Env
+}
+
+// Detail breaks down where a case's time and memory went. Whatever couldn't be measured is zero.
+type Detail struct {
+	// GCs is the number of garbage collections while sampling, and GCPauseNs their total pause.
+	GCs       int64 `json:"gcs"`
+	GCPauseNs int64 `json:"gc_pause_ns"`
+	// SieveBytesPerOp is the memory per operation allocated by the Primer itself, e.g. for its
+	// sieve; OtherBytesPerOp is everything else the call allocated, e.g. the output channel, the
+	// goroutine feeding it, and the closures that run the case.
+	SieveBytesPerOp int64 `json:"sieve_bytes_per_op"`
+	OtherBytesPerOp int64 `json:"other_bytes_per_op"`
+	// CacheMissesPerOp and InstructionsPerOp are from hardware counters, in user space.
+	CacheMissesPerOp  float64 `json:"cache_misses_per_op"`
+	InstructionsPerOp float64 `json:"instructions_per_op"`
+}
+
+// Env describes the environment a benchmark ran in.
+type Env struct {
+	GoVersion  string    `json:"go_version"`
+	GOMAXPROCS int       `json:"gomaxprocs"`
+	CPU        string    `json:"cpu"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// Name returns the human-readable name of the case, e.g. "IsPrime: Erat2(9973)".
+func (r Record) Name() string {
+	return fmt.Sprintf("%s: %s(%d)", r.Op, r.Impl, r.Arg)
+}
+
+// Format is an output format for Records.
+type Format string
+
+const (
+	JSON Format = "json"
+	CSV  Format = "csv"
+	// TSV is the format of results.tsv: the original columns, keyed by Name, followed by the rest.
+	TSV Format = "tsv"
+	// Benchstat is the output format of `go test -bench`, with a line per sample, for benchstat.
+	Benchstat Format = "benchstat"
+)
+
+// ParseFormat returns the Format named s.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case JSON, CSV, TSV, Benchstat:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format %q; want one of %s, %s, %s, %s", s, JSON, CSV, TSV, Benchstat)
+}
+
+var (
+	csvHeader = []string{
+		"op", "impl", "arg", "result",
+		"iterations", "ns_per_op", "bytes_per_op", "allocs_per_op",
+		"go_version", "gomaxprocs", "cpu", "timestamp",
+		"samples", "median_ns", "min_ns", "max_ns", "stddev_ns", "ci95_low_ns", "ci95_high_ns",
+		"gcs", "gc_pause_ns", "sieve_bytes_per_op", "other_bytes_per_op", "cache_misses_per_op", "instructions_per_op",
+	}
+	tsvHeader = []string{
+		"Name", "Result", "Iterations", "Total time", "Avg time (ns)", "Avg memory (bytes)", "Avg allocs (ops)",
+		"Op", "Impl", "Arg", "Go version", "GOMAXPROCS", "CPU", "Timestamp",
+		"Samples", "Median (ns)", "Min (ns)", "Max (ns)", "Stddev (ns)", "95% CI low (ns)", "95% CI high (ns)",
+		"GCs", "GC pause (ns)", "Sieve memory (bytes)", "Other memory (bytes)", "Cache misses/op", "Instructions/op",
+	}
+)
+
+// Write writes records to w in format f.
+func Write(w io.Writer, f Format, records []Record) error {
+	switch f {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case CSV:
+		out := csv.NewWriter(w)
+		out.Write(csvHeader)
+		for _, r := range records {
+			out.Write(append([]string{
+				r.Op, r.Impl, strconv.Itoa(r.Arg), r.Result,
+				strconv.Itoa(r.Iterations), formatFloat(r.NsPerOp), formatInt(r.BytesPerOp), formatInt(r.AllocsPerOp),
+				r.GoVersion, strconv.Itoa(r.GOMAXPROCS), r.CPU, r.Timestamp.Format(time.RFC3339),
+			}, append(r.Stats.columns(), r.Detail.columns()...)...))
+		}
+		out.Flush()
+		return out.Error()
+	case TSV:
+		// Not csv.Writer: results.tsv doesn't quote, and ends each line with a tab.
+		writeRow := func(row ...string) error {
+			for _, v := range row {
+				if _, err := fmt.Fprintf(w, "%s\t", v); err != nil {
+					return err
+				}
+			}
+			_, err := fmt.Fprintln(w)
+			return err
+		}
+		if err := writeRow(tsvHeader...); err != nil {
+			return err
+		}
+		for _, r := range records {
+			total := time.Duration(r.NsPerOp * float64(r.Iterations))
+			row := append([]string{
+				r.Name(), r.Result, strconv.Itoa(r.Iterations), total.String(),
+				formatInt(int64(r.NsPerOp)), formatInt(r.BytesPerOp), formatInt(r.AllocsPerOp),
+				r.Op, r.Impl, strconv.Itoa(r.Arg),
+				r.GoVersion, strconv.Itoa(r.GOMAXPROCS), r.CPU, r.Timestamp.Format(time.RFC3339),
+			}, append(r.Stats.columns(), r.Detail.columns()...)...)
+			if err := writeRow(row...); err != nil {
+				return err
+			}
+		}
+		return nil
+	case Benchstat:
+		return writeBenchstat(w, records)
+	}
+	return fmt.Errorf("unknown format %q", f)
+}
+
+// columns returns s's fields, as CSV or TSV columns.
+func (s Stats) columns() []string {
+	return []string{
+		strconv.Itoa(s.Samples), formatFloat(s.Median), formatFloat(s.Min), formatFloat(s.Max),
+		formatFloat(s.Stddev), formatFloat(s.CILow), formatFloat(s.CIHigh),
+	}
+}
+
+// columns returns d's fields, as CSV or TSV columns.
+func (d Detail) columns() []string {
+	return []string{
+		formatInt(d.GCs), formatInt(d.GCPauseNs), formatInt(d.SieveBytesPerOp), formatInt(d.OtherBytesPerOp),
+		formatFloat(d.CacheMissesPerOp), formatFloat(d.InstructionsPerOp),
+	}
+}
+
+func formatInt(v int64) string {
+	return strconv.FormatInt(v, 10)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
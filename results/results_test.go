@@ -0,0 +1,142 @@
+package results
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testRecords = []Record{
+	{
+		Op: "IsPrime", Impl: "Erat2", Arg: 9973, Result: "true",
+		Iterations: 10000, NsPerOp: 192266, BytesPerOp: 5476, AllocsPerOp: 2,
+		Detail: Detail{GCs: 3, GCPauseNs: 12000, SieveBytesPerOp: 5376, OtherBytesPerOp: 100, InstructionsPerOp: 1.5e6},
+		Env: Env{
+			GoVersion: "go1.21", GOMAXPROCS: 4, CPU: "Some CPU",
+			Timestamp: time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+	},
+}
+
+func TestName(t *testing.T) {
+	if got, want := testRecords[0].Name(), "IsPrime: Erat2(9973)"; got != want {
+		t.Errorf("unexpected name: got: %q want: %q", got, want)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	for _, c := range []struct {
+		f    Format
+		want string
+	}{
+		{CSV, "IsPrime,Erat2,9973,true,10000,192266,5476,2,go1.21,4,Some CPU,2016-01-02T03:04:05Z,0,0,0,0,0,0,0,3,12000,5376,100,0,1500000\n"},
+		{TSV, "IsPrime: Erat2(9973)\ttrue\t10000\t1.92266s\t192266\t5476\t2\tIsPrime\tErat2\t9973\tgo1.21\t4\tSome CPU\t2016-01-02T03:04:05Z\t"},
+		{JSON, `"ns_per_op": 192266,`},
+		{Benchstat, "BenchmarkIsPrime/Erat2/9973-4\t10000\t192266 ns/op\t5476 B/op\t2 allocs/op\n"},
+	} {
+		var buf bytes.Buffer
+		if err := Write(&buf, c.f, testRecords); err != nil {
+			t.Errorf("for format %s: got error: %v", c.f, err)
+		}
+		if got := buf.String(); !strings.Contains(got, c.want) {
+			t.Errorf("for format %s: got: %q want it to contain: %q", c.f, got, c.want)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	for _, f := range []Format{JSON, CSV, TSV} {
+		// (Benchstat only carries the samples; see TestReadBenchstat.)
+		var buf bytes.Buffer
+		if err := Write(&buf, f, testRecords); err != nil {
+			t.Fatalf("for format %s: got error: %v", f, err)
+		}
+		got, err := Read(&buf)
+		if err != nil {
+			t.Errorf("for format %s: got error: %v", f, err)
+		}
+		if !reflect.DeepEqual(got, testRecords) {
+			t.Errorf("for format %s: got: %+v want: %+v", f, got, testRecords)
+		}
+	}
+}
+
+func TestReadLegacy(t *testing.T) {
+	// The head of the original results.tsv, from github.com/cceckman/bencher.
+	in := "Name\tResult\tIterations\tTotal time\tAvg time (ns)\tAvg memory (bytes)\tAvg allocs (ops)\t\n" +
+		"IsPrime: Erat2(9973)\ttrue\t10000\t1.922664004s\t192266\t5476\t2\t\n" +
+		"PrimesUpTo: SimpleErat(1000001)\t999983\t100\t1.554161298s\t15541612\t1007720\t2\t\n"
+	got, err := Read(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	want := []Record{
+		{Op: "IsPrime", Impl: "Erat2", Arg: 9973, Result: "true", Iterations: 10000, NsPerOp: 192266, BytesPerOp: 5476, AllocsPerOp: 2},
+		{Op: "PrimesUpTo", Impl: "SimpleErat", Arg: 1000001, Result: "999983", Iterations: 100, NsPerOp: 15541612, BytesPerOp: 1007720, AllocsPerOp: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %+v want: %+v", got, want)
+	}
+}
+
+func TestReadChannelMemory(t *testing.T) {
+	// Before OtherBytesPerOp was renamed, it was "channel" memory.
+	in := "op,impl,arg,sieve_bytes_per_op,channel_bytes_per_op\n" +
+		"PrimesUpTo,Erat5,101,56,336\n"
+	got, err := Read(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if len(got) != 1 || got[0].Detail.SieveBytesPerOp != 56 || got[0].Detail.OtherBytesPerOp != 336 {
+		t.Errorf("got: %+v want SieveBytesPerOp 56, OtherBytesPerOp 336", got)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	samples := []Sample{{NsPerOp: 10}, {NsPerOp: 12}, {NsPerOp: 11}, {NsPerOp: 13}}
+	st, mean := Summarize(samples)
+	if mean != 11.5 || st.Samples != 4 || st.Min != 10 || st.Max != 13 || st.Median != 11.5 {
+		t.Errorf("unexpected summary: got: %+v, mean %v", st, mean)
+	}
+	// stddev = sqrt(5/3); t(3) = 3.182
+	if half := st.CIHigh - mean; half < 2.05 || half > 2.06 {
+		t.Errorf("unexpected confidence interval: got: [%v, %v]", st.CILow, st.CIHigh)
+	}
+
+	other, _ := Summarize([]Sample{{NsPerOp: 20}, {NsPerOp: 21}, {NsPerOp: 20}})
+	if st.Overlaps(other) {
+		t.Errorf("unexpected overlap: %+v and %+v", st, other)
+	}
+	other, _ = Summarize([]Sample{{NsPerOp: 12}, {NsPerOp: 14}})
+	if !st.Overlaps(other) {
+		t.Errorf("unexpected lack of overlap: %+v and %+v", st, other)
+	}
+}
+
+func TestReadBenchstat(t *testing.T) {
+	in := "goos: linux\n" +
+		"cpu: Some CPU\n" +
+		"BenchmarkIsPrime/Erat2/9973-4\t10000\t10 ns/op\t5476 B/op\t2 allocs/op\n" +
+		"BenchmarkIsPrime/Erat2/9973-4\t10000\t12 ns/op\t5476 B/op\t2 allocs/op\n" +
+		"BenchmarkPrimesUpTo/Memo/101\t100\t30 ns/op\n" +
+		"BenchmarkPrimesUpTo/Memo/cold/101\t100\t40 ns/op\n" +
+		"PASS\n"
+	got, err := Read(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("unexpected number of records: got: %d want: %d", len(got), 3)
+	}
+	if r := got[0]; r.Key() != (Key{"IsPrime", "Erat2", 9973}) || r.NsPerOp != 11 || r.Stats.Samples != 2 || r.GOMAXPROCS != 4 || r.CPU != "Some CPU" {
+		t.Errorf("unexpected record: %+v", r)
+	}
+	if r := got[1]; r.Key() != (Key{"PrimesUpTo", "Memo", 101}) || r.NsPerOp != 30 || r.Iterations != 100 {
+		t.Errorf("unexpected record: %+v", r)
+	}
+	if r := got[2]; r.Key() != (Key{"PrimesUpTo", "Memo/cold", 101}) || r.NsPerOp != 40 {
+		t.Errorf("unexpected record: %+v", r)
+	}
+}
@@ -0,0 +1,89 @@
+package results
+
+import (
+	"math"
+	"sort"
+)
+
+// Sample is a single, independent measurement of a case.
+type Sample struct {
+	Iterations  int     `json:"iterations"`
+	NsPerOp     float64 `json:"ns_per_op"`
+	BytesPerOp  int64   `json:"bytes_per_op"`
+	AllocsPerOp int64   `json:"allocs_per_op"`
+}
+
+// Stats summarizes the time per operation, in nanoseconds, across a case's Samples.
+type Stats struct {
+	Samples int     `json:"samples"`
+	Median  float64 `json:"median_ns"`
+	Min     float64 `json:"min_ns"`
+	Max     float64 `json:"max_ns"`
+	Stddev  float64 `json:"stddev_ns"`
+	// CILow and CIHigh bound the 95% confidence interval for the mean.
+	CILow  float64 `json:"ci95_low_ns"`
+	CIHigh float64 `json:"ci95_high_ns"`
+}
+
+// tTable holds the two-sided 95% critical values of Student's t distribution,
+// indexed by degrees of freedom.
+var tTable = []float64{
+	math.Inf(1), 12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262,
+	2.228, 2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093,
+	2.086, 2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045,
+	2.042,
+}
+
+// tCritical returns the two-sided 95% critical value of Student's t distribution for df degrees
+// of freedom.
+func tCritical(df int) float64 {
+	if df < len(tTable) {
+		return tTable[df]
+	}
+	// Close enough to the normal distribution.
+	return 1.960
+}
+
+// Summarize returns the Stats of samples' times, and their mean.
+func Summarize(samples []Sample) (Stats, float64) {
+	n := len(samples)
+	if n == 0 {
+		return Stats{}, 0
+	}
+	ns := make([]float64, n)
+	sum := 0.0
+	for i, s := range samples {
+		ns[i] = s.NsPerOp
+		sum += s.NsPerOp
+	}
+	sort.Float64s(ns)
+	mean := sum / float64(n)
+
+	st := Stats{
+		Samples: n,
+		Min:     ns[0],
+		Max:     ns[n-1],
+		Median:  ns[n/2],
+		CILow:   mean,
+		CIHigh:  mean,
+	}
+	if n%2 == 0 {
+		st.Median = (ns[n/2-1] + ns[n/2]) / 2
+	}
+	if n > 1 {
+		ss := 0.0
+		for _, v := range ns {
+			ss += (v - mean) * (v - mean)
+		}
+		st.Stddev = math.Sqrt(ss / float64(n-1))
+		half := tCritical(n-1) * st.Stddev / math.Sqrt(float64(n))
+		st.CILow, st.CIHigh = mean-half, mean+half
+	}
+	return st, mean
+}
+
+// Overlaps returns whether the confidence intervals of s and o overlap- i.e., whether we can't
+// tell them apart with 95% confidence.
+func (s Stats) Overlaps(o Stats) bool {
+	return s.CILow <= o.CIHigh && o.CILow <= s.CIHigh
+}
@@ -0,0 +1,237 @@
+package primes
+
+import (
+	"fmt"
+	"iter"
+	"math"
+)
+
+// defaultSegmentSize is the number of odd numbers segErat sieves at a time, if its memory budget
+// allows. 256KiB of bools is about the size of a fast cache.
+const defaultSegmentSize = 1 << 18
+
+// minSegmentSize is the smallest segment worth sieving; a budget that leaves less than this
+// isn't really a budget.
+const minSegmentSize = 1 << 10
+
+// isqrt returns the largest integer whose square is less than or equal to n.
+func isqrt(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	r := int(math.Sqrt(float64(n)))
+	// The float may be off by one in either direction, for large n.
+	// Compare by division so as not to overflow.
+	for r > n/r {
+		r--
+	}
+	for r+1 <= n/(r+1) {
+		r++
+	}
+	return r
+}
+
+// sieveWindow finds the primes among the odd numbers in [lo, hi], calling found for each in order.
+// lo must be odd. composite is scratch space, with room for at least (hi-lo)/2+1 odd numbers;
+// composite[k] refers to the number lo+2k.
+// known must hold, in order, every prime below lo up to sqrt(hi) (it may hold more).
+// Primes found within the window are sieved as they're found, so the window can extend past
+// the square of the largest known prime.
+func sieveWindow(composite []bool, lo, hi int, known *primeList, found func(int)) {
+	size := (hi-lo)/2 + 1
+	composite = composite[:size]
+	clear(composite)
+	if lo == 1 {
+		composite[0] = true // 1 is not prime
+	}
+
+	// First, mark composites from already-known primes.
+	// Skip the first prime (2), since it isn't even in our array.
+	for k := 1; k < known.len(); k++ {
+		prime := known.at(k)
+		if prime >= lo || prime > hi/prime {
+			// Either we'll find this prime (and sieve with it) in the window, or its square is
+			// beyond the window; the same goes for all the later ones.
+			break
+		}
+		// Start with the first odd multiple of prime in the window, but no lower than prime*prime.
+		// (All smaller multiples of prime have another prime factor, that is smaller than prime.)
+		start := prime * prime
+		if start < lo {
+			start = lo + (prime-lo%prime)%prime
+			if start%2 == 0 {
+				start += prime
+			}
+		}
+		for j := (start - lo) / 2; j < size; j += prime {
+			composite[j] = true
+		}
+	}
+
+	// Now, walk up, checking / marking composites along the way.
+	for k := range composite {
+		if composite[k] { // non-default; has been explicitly set to be composite.
+			continue
+		}
+		i := lo + 2*k
+		found(i)
+
+		if i > hi/i {
+			// Skip sieving; its square is beyond the window.
+			continue
+		}
+		// Odd multiples are 2i apart, i.e. i indices apart.
+		for j := (i*i - lo) / 2; j < size; j += i {
+			composite[j] = true
+		}
+	}
+}
+
+// sieveRange finds the primes among the odd numbers in [lo, hi], calling found for each in order,
+// until it returns false. It sieves len(composite) odd numbers at a time, so composite bounds its
+// memory however wide the range is. As for sieveWindow, lo must be odd, and known must hold every
+// prime below lo up to sqrt(hi).
+func sieveRange(composite []bool, lo, hi int, known *primeList, found func(int) bool) {
+	stopped := false
+	for !stopped && lo <= hi {
+		// Sieve [lo, end]; don't let end go past hi, or overflow.
+		end := hi
+		if span := 2 * (len(composite) - 1); lo <= hi-span {
+			end = lo + span
+		}
+		sieveWindow(composite, lo, end, known, func(i int) {
+			stopped = stopped || !found(i)
+		})
+		lo = end + 2
+	}
+}
+
+// basePrimes returns the primes up to limit, found in one go. (The list always starts with 2.)
+// They're all the primes that sieving up to limit^2 needs.
+func basePrimes(limit int) primeList {
+	base := newPrimeList(2)
+	if limit >= 3 {
+		sieveWindow(make([]bool, limit/2), 3, limit, &base, base.append)
+	}
+	return base
+}
+
+var (
+	_ Primer = NewSegmentedErat()
+)
+
+// segErat is a segmented Sieve of Eratosthenes: it finds the primes up to sqrt(n), then sieves
+// the rest of the range one fixed-size window at a time. Its memory use is bounded by the window,
+// rather than growing with n.
+type segErat struct {
+	opts options
+}
+
+// NewSegmentedErat returns a segmented sieve. WithMaxMemory bounds the size of its segments.
+func NewSegmentedErat(opts ...Option) Primer {
+	return &segErat{
+		opts: newOptions(opts),
+	}
+}
+
+// segmentSize returns the number of odd numbers to sieve at a time for primes up to n,
+// or an error if the budget can't accommodate the primes up to sqrt(n) plus a minimal segment.
+func (p *segErat) segmentSize(n int) (int, error) {
+	if n > MaxN {
+		return 0, fmt.Errorf("%w: %d is greater than %d", ErrTooLarge, n, MaxN)
+	}
+	sqrt := isqrt(n)
+	// The base primes are found with a sieve of their own, then listed.
+	base := sqrt/2 + 1 + piUpperBound(sqrt)*intSize
+	avail := p.opts.maxMemory - base
+	if avail < minSegmentSize {
+		return 0, fmt.Errorf("%w: sieving up to %d needs more than %d bytes", ErrOutOfMemoryBudget, n, p.opts.maxMemory)
+	}
+	return min(avail, defaultSegmentSize, n/2+1), nil
+}
+
+func (p *segErat) check(n int) error {
+	_, err := p.segmentSize(n)
+	return err
+}
+
+// PrimesUpTo streams the primes up to n to out. If n is out of budget, it closes out without
+// sending anything; use PrimesUpToE to get an error instead.
+func (p *segErat) PrimesUpTo(n int, out chan<- int) {
+	defer close(out)
+	if n <= 1 {
+		return
+	}
+	size, err := p.segmentSize(n)
+	if err != nil {
+		return
+	}
+	out <- 2
+
+	// The primes up to sqrt(n) are the only ones we need to sieve with.
+	base := basePrimes(isqrt(n))
+	sieveRange(make([]bool, size), 3, n, &base, func(i int) bool {
+		out <- i
+		return true
+	})
+}
+
+func (p *segErat) IsPrime(n int) bool {
+	if n <= 1 {
+		return false
+	}
+	if n == 2 {
+		return true
+	}
+	if n%2 == 0 {
+		return false
+	}
+
+	c := make(chan int)
+
+	// It's no more expensive to compute all primes up to n
+	// with a sieve of Eratosthenes, vs. just computing
+	// whether n is prime.
+	go p.PrimesUpTo(n, c)
+	for p := range c {
+		if p == n {
+			return true
+		}
+	}
+	return false
+}
+
+// PrimesBetween returns the primes in [lo, hi], in order. hi is capped at MaxN.
+// It sieves just the range, a segment at a time as it's iterated, so it's quick for a narrow range
+// however large lo is; its memory grows only with sqrt(hi), as NewSegmentedErat's does.
+// Check(hi, NewSegmentedErat()) reports whether that fits in the default budget.
+func PrimesBetween(lo, hi int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		primesBetween(lo, hi, yield)
+	}
+}
+
+// primesBetween calls f with each prime in [lo, hi], in order, until f returns false.
+// hi is capped at MaxN. Like segErat, it sieves one segment at a time.
+func primesBetween(lo, hi int, f func(int) bool) {
+	hi = min(hi, MaxN)
+	lo = max(lo, 2)
+	if lo > hi {
+		return
+	}
+	if lo == 2 {
+		if !f(2) {
+			return
+		}
+		lo = 3
+	}
+	// Start with lo or lo+1, whichever is odd.
+	lo += 1 - lo%2
+	if lo > hi {
+		return
+	}
+
+	// The primes up to sqrt(hi) are the only ones we need to sieve with.
+	base := basePrimes(isqrt(hi))
+	sieveRange(make([]bool, min(defaultSegmentSize, (hi-lo)/2+1)), lo, hi, &base, f)
+}
@@ -2,17 +2,16 @@ package primes
 
 import (
 	"math"
-	"sort"
 )
 
 // DB is a thread-unsafe memoized list of prime numbers.
 type DB struct {
-	primes []int
+	primes primeList
 }
 
 func New() *DB {
 	return &DB{
-		primes: []int{2, 3, 5, 7},
+		primes: newPrimeList(2, 3, 5, 7),
 	}
 }
 
@@ -24,15 +23,15 @@ type Iterator struct {
 
 // Next returns the next prime number and advances the iterator.
 func (i *Iterator) Next() int {
-	if i.index == len(i.parent.primes) {
+	if i.index == i.parent.primes.len() {
 		// Need to grow the list.
 		// Arbitrarily choose max*2 as the factor.
-		max := i.parent.primes[i.index-1]
+		max := i.parent.primes.at(i.index - 1)
 		i.parent.computeBeyond(2 * max)
 	}
 
 	i.index +=1
-	return i.parent.primes[i.index-1]
+	return i.parent.primes.at(i.index - 1)
 }
 
 // Iterator returns a new Iterator backed by this DB.
@@ -58,14 +57,13 @@ func (p *DB) IsPrime(n int) bool {
 	// Ensure that we have enough in the list.
 	p.computeBeyond(n)
 
-	i := sort.SearchInts(p.primes, n)
-	return i < len(p.primes) && p.primes[i] == n
+	return p.primes.contains(n)
 }
 
 // computeBeyond is a thread-unsave blocking call that returns once p has computed a prime greater than m.
 func (p *DB) computeBeyond(m int) {
 	// Do we know at least one prime beyond the requested number?
-	max := p.primes[len(p.primes)-1]
+	max := p.primes.last()
 	if m < max {
 		return
 	}
@@ -73,7 +71,7 @@ func (p *DB) computeBeyond(m int) {
 
 	// In order for callers to guarantee progress, we only want to
 	// return if we've added primes to our list.
-	for n, initLen := m, len(p.primes); len(p.primes) == initLen; n = n*2 + 1 {
+	for n, initLen := m, p.primes.len(); p.primes.len() == initLen; n = grow(n) {
 
 		// In an odds-only slice,
 		// index i refers to the number (i*2)+1;
@@ -85,7 +83,12 @@ func (p *DB) computeBeyond(m int) {
 
 		// First, mark composites from already-known primes.
 		// Skip the first prime (2), since it isn't even in our array.
-		for _, prime := range p.primes[1:] {
+		for k := 1; k < p.primes.len(); k++ {
+			prime := p.primes.at(k)
+			if prime > n/prime {
+				// prime*prime is beyond n (and may not even fit in an int); so are all the later ones.
+				break
+			}
 			// We could optimize this by starting at the first *odd* multiple of prime greater than or
 			// equal to p.max. Instead, just start at prime*prime. (All smaller multiples of prime have
 			// another prime factor, that is smaller than prime.)
@@ -105,7 +108,7 @@ func (p *DB) computeBeyond(m int) {
 				continue
 			}
 			// Found a prime; record it.
-			p.primes = append(p.primes, int(i))
+			p.primes.append(i)
 
 			if i > sqrt {
 				// Skip sieving; we've covered all the primes already.
@@ -122,3 +125,11 @@ func (p *DB) computeBeyond(m int) {
 		}
 	}
 }
+
+// grow returns the next bound for computeBeyond to try after n: n*2+1, but without overflowing.
+func grow(n int) int {
+	if n > (MaxN-1)/2 {
+		return MaxN
+	}
+	return n*2 + 1
+}
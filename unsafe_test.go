@@ -11,7 +11,7 @@ func TestIteratorNext(t *testing.T) {
 	for i, p := range refPrimes {
 		c := it.Next()
 		if c != p {
-			t.Error("unexpected prime #%d: got: %d want: %d", i, c, p)
+			t.Errorf("unexpected prime #%d: got: %d want: %d", i, c, p)
 		}
 	}
 }
@@ -29,7 +29,7 @@ func TestDB(t *testing.T) {
 
 		got := db.IsPrime(n)
 		if got != want {
-			t.Errorf("unexpected primacy for %d: got: %v want: %v", got, want)
+			t.Errorf("unexpected primacy for %d: got: %v want: %v", n, got, want)
 		}
 	}
 }